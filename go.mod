@@ -0,0 +1,5 @@
+module github.com/kryptonlang/krypton
+
+go 1.21
+
+require golang.org/x/text v0.14.0
@@ -0,0 +1,94 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// File represents a named krypton source file. Positions inside a
+// File can be resolved into fully-qualified name:line:column strings
+// which identify the file they belong to.
+type File struct {
+	name   string
+	source io.Reader
+
+	size  int   // size of the source in bytes, if known
+	lines []int // byte offsets at which each line starts, if known
+}
+
+// NewFile creates a new File with the given name and source.
+func NewFile(name string, source io.Reader) *File {
+	return &File{name: name, source: source}
+}
+
+// NewSourceFile creates a new File with the given name from the full
+// source text. Unlike NewFile, it precomputes the byte offset at
+// which each line starts, so byte offsets into the source can be
+// converted back into positions with Position.
+func NewSourceFile(name, source string) *File {
+	lines := []int{0}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	return &File{
+		name:   name,
+		source: strings.NewReader(source),
+		size:   len(source),
+		lines:  lines,
+	}
+}
+
+// Position converts a byte offset into the source into the position
+// of the character at that offset, with the column counted in bytes
+// from the start of the line. The offset of a newline resolves to
+// the final position of the line it terminates, while the offset
+// directly after it resolves to the start of the next line.
+//
+// Out-of-range offsets are clamped to the source's bounds, and files
+// without precomputed line offsets resolve every offset to Origin.
+func (f *File) Position(offset int) Pos {
+	if len(f.lines) == 0 {
+		return Origin
+	}
+
+	offset = max(0, min(offset, f.size))
+
+	line := sort.Search(len(f.lines), func(i int) bool {
+		return f.lines[i] > offset
+	}) - 1
+
+	return Pos{Line: line + 1, Column: offset - f.lines[line] + 1}
+}
+
+// Name returns the name of the file.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Source returns the reader which provides the file's contents.
+func (f *File) Source() io.Reader {
+	return f.source
+}
+
+// ResolvePos resolves the given position into a fully-qualified
+// string in the name:line:column format.
+func (f *File) ResolvePos(pos Pos) string {
+	return fmt.Sprintf("%s:%v", f.name, pos)
+}
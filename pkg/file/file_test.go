@@ -0,0 +1,95 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPosition(t *testing.T) {
+	source := "let x = 1\nlet y = 2\n"
+	file := NewSourceFile("main.kr", source)
+
+	cases := []struct {
+		offset   int
+		expected Pos
+	}{
+		{0, Pos{Line: 1, Column: 1}},
+		{4, Pos{Line: 1, Column: 5}},
+		{9, Pos{Line: 1, Column: 10}},  // the first newline itself
+		{10, Pos{Line: 2, Column: 1}},  // directly after it
+		{19, Pos{Line: 2, Column: 10}}, // the final newline
+		{20, Pos{Line: 3, Column: 1}},  // end of the source
+		{-5, Pos{Line: 1, Column: 1}},  // clamped to the start
+		{99, Pos{Line: 3, Column: 1}},  // clamped to the end
+	}
+
+	for _, c := range cases {
+		if pos := file.Position(c.offset); pos != c.expected {
+			t.Errorf("Position(%d) = %v, expected %v", c.offset, pos, c.expected)
+		}
+	}
+}
+
+func TestFileSetResolve(t *testing.T) {
+	set := NewFileSet()
+	a := set.Add("a.kr", "let x = 1\n") // 10 bytes, global offsets 0-10
+	b := set.Add("b.kr", "let y = 2\n") // 10 bytes, global offsets 11-21
+
+	cases := []struct {
+		offset   int
+		file     *File
+		expected Pos
+	}{
+		{-1, a, Pos{Line: 1, Column: 1}}, // clamped to the first file
+		{0, a, Pos{Line: 1, Column: 1}},
+		{4, a, Pos{Line: 1, Column: 5}},
+		{10, a, Pos{Line: 2, Column: 1}}, // end of the first file
+		{11, b, Pos{Line: 1, Column: 1}}, // start of the second
+		{15, b, Pos{Line: 1, Column: 5}},
+		{99, b, Pos{Line: 2, Column: 1}}, // clamped to the last file
+	}
+
+	for _, c := range cases {
+		file, pos := set.Resolve(c.offset)
+		if file != c.file || pos != c.expected {
+			t.Errorf(
+				"Resolve(%d) = %s:%v, expected %s:%v",
+				c.offset, file.Name(), pos, c.file.Name(), c.expected,
+			)
+		}
+	}
+
+	if base := set.Base(b); base != 11 {
+		t.Errorf("Base(b.kr) = %d, expected 11", base)
+	}
+
+	if base := set.Base(NewSourceFile("c.kr", "")); base != -1 {
+		t.Errorf("Base on a file outside the set = %d, expected -1", base)
+	}
+}
+
+func TestFileSetResolveEmpty(t *testing.T) {
+	if file, pos := NewFileSet().Resolve(5); file != nil || pos != Origin {
+		t.Errorf("Resolve on an empty set = %v:%v, expected <nil>:%v", file, pos, Origin)
+	}
+}
+
+func TestPositionStreamFile(t *testing.T) {
+	file := NewFile("main.kr", strings.NewReader("let x = 1"))
+
+	if pos := file.Position(4); pos != Origin {
+		t.Errorf("Position on a stream file = %v, expected %v", pos, Origin)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import "sort"
+
+// FileSet is an ordered collection of source files processed
+// together, like the includes assembled into one program. Each file
+// occupies a half-open range of a single global byte offset space,
+// so an offset into the concatenated program resolves back to the
+// file, line, and column it came from, modeled on go/token.FileSet.
+type FileSet struct {
+	files []*File
+	bases []int // global base offset of each file
+	size  int   // total extent of the set in bytes
+}
+
+// NewFileSet creates an empty FileSet.
+func NewFileSet() *FileSet {
+	return &FileSet{}
+}
+
+// Add creates a File with the given name and source text, appends it
+// to the set at the next free base offset, and returns it.
+func (set *FileSet) Add(name, source string) *File {
+	file := NewSourceFile(name, source)
+
+	set.files = append(set.files, file)
+	set.bases = append(set.bases, set.size)
+
+	// the extra offset keeps the end of this file distinct from the
+	// start of the next one
+	set.size += len(source) + 1
+
+	return file
+}
+
+// Files returns the files of the set, in the order added.
+func (set *FileSet) Files() []*File {
+	return set.files
+}
+
+// Base returns the global base offset at which the given file was
+// added to the set, or -1 if the file is not part of it.
+func (set *FileSet) Base(file *File) int {
+	for i, added := range set.files {
+		if added == file {
+			return set.bases[i]
+		}
+	}
+
+	return -1
+}
+
+// Resolve converts a global byte offset into the file it lands in
+// and the position inside that file. Out-of-range offsets clamp to
+// the bounds of the set, and an empty set resolves every offset to a
+// nil file at Origin.
+func (set *FileSet) Resolve(offset int) (*File, Pos) {
+	if len(set.files) == 0 {
+		return nil, Origin
+	}
+
+	if offset < 0 {
+		offset = 0
+	} else if offset >= set.size {
+		offset = set.size - 1
+	}
+
+	index := sort.Search(len(set.bases), func(i int) bool {
+		return set.bases[i] > offset
+	}) - 1
+
+	file := set.files[index]
+	return file, file.Position(offset - set.bases[index])
+}
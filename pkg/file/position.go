@@ -0,0 +1,51 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package file provides types which represent positions inside
+// krypton source files.
+package file
+
+import "fmt"
+
+// Pos represents a position inside a source file, identified by its
+// line and column numbers.
+type Pos struct {
+	Line   int `json:"line"`   // line number, starting at 1
+	Column int `json:"column"` // column number, starting at 1
+}
+
+// Origin is the position of the first character of every source file.
+var Origin = Pos{Line: 1, Column: 1}
+
+// String returns a representation of the position in the standard
+// line:column format. The receiver is a value, so both Pos and *Pos
+// satisfy fmt.Stringer.
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// NextLine moves the position to the start of the next line.
+func (p *Pos) NextLine() {
+	p.Line++
+	p.Column = 1
+}
+
+// NextCharacter moves the position one character to the right.
+func (p *Pos) NextCharacter() {
+	p.Column++
+}
+
+// NextTab moves the position to the next tab stop of the given
+// width. With a width of 1, it is equivalent to NextCharacter.
+func (p *Pos) NextTab(width int) {
+	p.Column = ((p.Column-1)/width+1)*width + 1
+}
@@ -0,0 +1,39 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import "fmt"
+
+// Range represents a span inside a source file, from its Start
+// position up to, but not including, its End position.
+type Range struct {
+	Start Pos // position of the span's first character
+	End   Pos // position just past the span's last character
+}
+
+// String returns a representation of the range in the standard
+// line:column-line:column format, like "1:5-1:12".
+func (r Range) String() string {
+	return fmt.Sprintf("%v-%v", r.Start, r.End)
+}
+
+// Contains reports whether the range contains the given position.
+func (r Range) Contains(p Pos) bool {
+	return !isBefore(p, r.Start) && isBefore(p, r.End)
+}
+
+// isBefore reports whether position a comes before position b in the
+// source.
+func isBefore(a, b Pos) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
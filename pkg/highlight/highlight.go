@@ -0,0 +1,102 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package highlight renders krypton source code as syntax-highlighted
+// HTML for documentation sites and similar tooling.
+package highlight
+
+import (
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/kryptonlang/krypton/pkg/lexer"
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// HighlightHTML lexes the given source and writes it to the given
+// writer as HTML, wrapping each token in a span carrying a kr-
+// prefixed class for its kind, like <span class="kr-keyword">. The
+// whitespace between tokens is reproduced faithfully, so the emitted
+// HTML renders exactly like the source inside a <pre> block.
+//
+// Errors encountered while lexing are returned as a lexer.ErrorList
+// after the whole source has been rendered; errors writing to the
+// writer abort rendering immediately.
+func HighlightHTML(source io.Reader, writer io.Writer) error {
+	var errors lexer.ErrorList
+	scanner := lexer.NewScanner(source, errors.Add, lexer.KeepWhitespace())
+
+	for {
+		tok := scanner.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+
+		if err := writeToken(writer, tok); err != nil {
+			return err
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
+
+// writeToken writes a single token to the writer, escaped and
+// wrapped in its highlighting span. Tokens without a class, like
+// whitespace and inserted semicolons, are written bare.
+func writeToken(writer io.Writer, tok token.Token) error {
+	escaped := html.EscapeString(tok.Literal)
+
+	class := classOf(tok)
+	if class == "" {
+		_, err := io.WriteString(writer, escaped)
+		return err
+	}
+
+	_, err := fmt.Fprintf(writer, `<span class=%q>%s</span>`, class, escaped)
+	return err
+}
+
+// classOf returns the CSS class of the given token, or the empty
+// string for tokens which are rendered without one.
+func classOf(tok token.Token) string {
+	switch {
+	case tok.Type == token.Comment || tok.Type == token.DocComment:
+		return "kr-comment"
+
+	case token.IsLiteral(tok.Type):
+		switch tok.Type {
+		case token.Identifier:
+			return "kr-ident"
+		case token.String, token.Rune:
+			return "kr-string"
+		default:
+			return "kr-number"
+		}
+
+	// keywords and operators always have their canonical
+	// representation as their literal, so the string-based
+	// classifiers apply. Inserted semicolons keep their newline
+	// literal and are deliberately rendered bare.
+	case token.IsKeyword(tok.Literal):
+		return "kr-keyword"
+	case token.IsOperator(tok.Literal):
+		return "kr-operator"
+
+	default:
+		return ""
+	}
+}
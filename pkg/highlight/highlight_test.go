@@ -0,0 +1,44 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package highlight
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightHTML(t *testing.T) {
+	source := "let s = \"a<b\" # ok\n" +
+		"\tprint(s, 42)\n"
+
+	expected := `<span class="kr-keyword">let</span> ` +
+		`<span class="kr-ident">s</span> ` +
+		`<span class="kr-operator">=</span> ` +
+		`<span class="kr-string">&#34;a&lt;b&#34;</span> ` +
+		`<span class="kr-comment"># ok</span>` + "\n\t" +
+		`<span class="kr-ident">print</span>` +
+		`<span class="kr-operator">(</span>` +
+		`<span class="kr-ident">s</span>` +
+		`<span class="kr-operator">,</span> ` +
+		`<span class="kr-number">42</span>` +
+		`<span class="kr-operator">)</span>` + "\n"
+
+	var builder strings.Builder
+	if err := HighlightHTML(strings.NewReader(source), &builder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if html := builder.String(); html != expected {
+		t.Errorf("rendered:\n%s\nexpected:\n%s", html, expected)
+	}
+}
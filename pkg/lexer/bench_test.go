@@ -0,0 +1,126 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// largeSource returns a synthetic source of roughly the given size,
+// featuring the long string and comment literals whose accumulation
+// dominates lexing time.
+func largeSource(size int) string {
+	snippet := `func process(data) {
+	let greeting = "` + strings.Repeat("hello world ", 32) + `"
+	# ` + strings.Repeat("a thoroughly padded comment ", 16) + `
+	return data ** 2 + 1_000_000
+}
+`
+
+	var builder strings.Builder
+	for builder.Len() < size {
+		builder.WriteString(snippet)
+	}
+
+	return builder.String()
+}
+
+// benchSnippets holds, for each kind of source a benchmark lexes, a
+// snippet dominated by tokens of that kind, repeated up to the
+// requested size by benchSource.
+var benchSnippets = map[string]string{
+	"Idents":   "alpha beta gamma delta epsilon zeta eta theta iota kappa\n",
+	"Numbers":  "0 42 1_000_000 0xFF_FF 0b1010 0o755 3.14159 6.02e23 0x1.8p3 2i\n",
+	"Strings":  `"short" "with \"escapes\" and é" '\n' "a longer padded string literal"` + "\n",
+	"Comments": "# an ordinary comment line of respectable length\n## a documentation comment to go with it\n",
+}
+
+// benchSource returns a synthetic source of roughly the given size,
+// built by repeating the snippet of the given kind.
+func benchSource(kind string, size int) string {
+	var builder strings.Builder
+	for builder.Len() < size {
+		builder.WriteString(benchSnippets[kind])
+	}
+
+	return builder.String()
+}
+
+// BenchmarkLex measures lexing throughput over sources of three
+// sizes, each dominated by identifiers, numbers, strings, or
+// comments, reporting allocations per token alongside the usual
+// per-operation numbers. It is the baseline against which literal
+// accumulation and token handoff optimizations are judged.
+func BenchmarkLex(b *testing.B) {
+	sizes := []struct {
+		name string
+		size int
+	}{
+		{"Small", 1 << 10},
+		{"Medium", 1 << 15},
+		{"Large", 1 << 20},
+	}
+
+	for _, kind := range []string{"Idents", "Numbers", "Strings", "Comments"} {
+		for _, size := range sizes {
+			b.Run(kind+"/"+size.name, func(b *testing.B) {
+				source := benchSource(kind, size.size)
+				b.SetBytes(int64(len(source)))
+				b.ReportAllocs()
+
+				var stats runtime.MemStats
+				runtime.ReadMemStats(&stats)
+				mallocs := stats.Mallocs
+
+				tokens := 0
+				for i := 0; i < b.N; i++ {
+					lexer := Lex(strings.NewReader(source), IgnoreErrors)
+					for tok := lexer.NextToken(); tok.Type != token.EOF; tok = lexer.NextToken() {
+						tokens++
+					}
+				}
+
+				runtime.ReadMemStats(&stats)
+				b.ReportMetric(float64(stats.Mallocs-mallocs)/float64(tokens), "allocs/token")
+			})
+		}
+	}
+}
+
+func BenchmarkLexer(b *testing.B) {
+	source := largeSource(1 << 21)
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		lexer := Lex(strings.NewReader(source), IgnoreErrors)
+		for tok := lexer.NextToken(); tok.Type != token.EOF; tok = lexer.NextToken() {
+		}
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	source := largeSource(1 << 21)
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		scanner := NewScanner(strings.NewReader(source), IgnoreErrors)
+		for tok := scanner.NextToken(); tok.Type != token.EOF; tok = scanner.NextToken() {
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"unicode/utf8"
+
+	"github.com/kryptonlang/krypton/pkg/file"
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// atComment reports whether a line comment starts at the current
+// rune, opened either by the configured introducer or by the
+// built-in C-style //. Both // and a two-rune introducer must match
+// in full, so a lone / still falls through to the operator lexer and
+// division keeps working.
+func (lexer *Lexer) atComment() bool {
+	return lexer.atIntroducer() || lexer.current == '/' && lexer.peek() == '/'
+}
+
+// atIntroducer reports whether the configured comment introducer
+// starts at the current rune.
+func (lexer *Lexer) atIntroducer() bool {
+	first, size := utf8.DecodeRuneInString(lexer.commentIntro)
+	if lexer.current != first {
+		return false
+	}
+
+	rest := lexer.commentIntro[size:]
+	if rest == "" {
+		return true
+	}
+
+	second, _ := utf8.DecodeRuneInString(rest)
+	return lexer.peek() == second
+}
+
+// lexComment lexes a line comment token. A comment which repeats its
+// introducer's final rune, like ## or ///, is documentation and
+// emits DocComment instead of Comment. The emitted literal includes
+// the leading markers but not the terminating newline.
+func (lexer *Lexer) lexComment() {
+	intro := lexer.commentIntro
+	if !lexer.atIntroducer() {
+		intro = "//"
+	}
+	// a #! at the very start of the source is a shebang line for
+	// direct script execution, not a comment: it is dropped without
+	// emitting anything. Anywhere else, #! is an ordinary comment.
+	if intro == "#" && lexer.tokenStart == file.Origin && lexer.peek() == '!' {
+		for lexer.current != '\n' && lexer.current != eof {
+			lexer.discard()
+		}
+
+		return
+	}
+
+	for range intro {
+		lexer.consume()
+	}
+
+	tokenType := token.Comment
+	if last, _ := utf8.DecodeLastRuneInString(intro); lexer.current == last {
+		tokenType = token.DocComment
+	}
+
+	insertSemi := lexer.insertSemi
+
+	for lexer.current != '\n' && lexer.current != eof {
+		lexer.consume()
+	}
+
+	lexer.emit(tokenType)
+
+	// comments do not affect semicolon insertion
+	lexer.insertSemi = insertSemi
+}
@@ -0,0 +1,150 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/kryptonlang/krypton/pkg/file"
+)
+
+// ErrorHandler is a callback which is called with every error
+// encountered while lexing a source.
+type ErrorHandler func(error)
+
+// IgnoreErrors is an ErrorHandler which ignores every error raised
+// by the lexer.
+var IgnoreErrors ErrorHandler = func(error) {}
+
+// The various errors raised by the lexer while lexing a source.
+var (
+	ErrIllegalUTF8 = errors.New("source contains illegal utf-8 encoding")
+	ErrIllegalBOM  = errors.New("illegal byte order mark")
+	ErrIllegalRune = errors.New("illegal rune in source")
+
+	ErrTooManyErrors = errors.New("too many errors")
+
+	ErrIndentMismatch = errors.New("unindent does not match any outer indentation level")
+
+	ErrMixedScript = errors.New("identifier mixes confusable scripts")
+
+	ErrUnclosedStringLit    = errors.New("string literal not terminated")
+	ErrUnclosedRuneLit      = errors.New("rune literal not terminated")
+	ErrEmptyRuneLiteral     = errors.New("empty rune literal")
+	ErrTooManyRuneChars     = errors.New("too many characters in rune literal")
+	ErrUnclosedEscapedIdent = errors.New("escaped identifier not terminated")
+	ErrEmptyEscapedIdent    = errors.New("empty escaped identifier")
+	ErrIllegalEscapedIdent  = errors.New("illegal rune in escaped identifier")
+
+	ErrIllegalEscape    = errors.New("unknown escape sequence")
+	ErrOctalEscapeRange = errors.New("octal escape value does not fit in a byte")
+	ErrInvalidCodepoint = errors.New("escape sequence is invalid Unicode codepoint")
+	ErrSurrogateEscape  = errors.New("escape sequence is a surrogate half: use \\U with the full code point instead")
+	ErrExpectedDigits   = errors.New("expected digits in numeric literal")
+	ErrDigitSeparator   = errors.New("'_' must separate successive digits")
+	ErrDecimalPowerExp  = errors.New("'p' exponent requires hexadecimal literal")
+	ErrHexFloatExponent = errors.New("hexadecimal float requires a 'p' exponent")
+	ErrNumberSuffix     = errors.New("invalid suffix on number literal")
+	ErrLegacyOctal      = errors.New("octal literal requires an explicit 0o prefix")
+)
+
+// Error represents an error raised while lexing, along with the
+// position in the source at which it was raised.
+type Error struct {
+	file *file.File
+	pos  file.Pos
+	err  error
+}
+
+// Error implements the error interface.
+func (err *Error) Error() string {
+	if err.file != nil {
+		return fmt.Sprintf("%s: %v", err.file.ResolvePos(err.pos), err.err)
+	}
+
+	return fmt.Sprintf("%v: %v", err.pos, err.err)
+}
+
+// Unwrap returns the underlying error.
+func (err *Error) Unwrap() error {
+	return err.err
+}
+
+// ErrorList collects the errors raised while lexing. Its Add method
+// can be used directly as an ErrorHandler:
+//
+//	var errors lexer.ErrorList
+//	scanner := lexer.NewScanner(source, errors.Add)
+type ErrorList []*Error
+
+// Add appends the given error to the list. Errors which are not of
+// type *Error, which the lexer never raises, are silently dropped.
+func (list *ErrorList) Add(err error) {
+	var lexerErr *Error
+	if errors.As(err, &lexerErr) {
+		*list = append(*list, lexerErr)
+	}
+}
+
+// Sorted returns a copy of the list sorted by position in the
+// source, with errors on the same line ordered by column.
+func (list ErrorList) Sorted() ErrorList {
+	sorted := make(ErrorList, len(list))
+	copy(sorted, list)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].pos, sorted[j].pos
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+
+	return sorted
+}
+
+// Error implements the error interface, summarizing the list by its
+// first error and the number of errors which follow it.
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	default:
+		return fmt.Sprintf("%v (and %d more errors)", list[0], len(list)-1)
+	}
+}
+
+// raise reports the given error at the current position to the
+// lexer's ErrorHandler. Once the lexer's error limit is reached, it
+// reports a final ErrTooManyErrors, flags the lexer as done, and
+// swallows every error raised after that.
+func (lexer *Lexer) raise(err error) {
+	if lexer.maxErrors > 0 && lexer.Errors >= lexer.maxErrors {
+		return
+	}
+
+	lexer.Errors++
+
+	if lexer.ErrorHandler != nil {
+		lexer.ErrorHandler(&Error{file: lexer.file, pos: lexer.tokenEnd, err: err})
+	}
+
+	if lexer.maxErrors > 0 && lexer.Errors == lexer.maxErrors {
+		if lexer.ErrorHandler != nil {
+			lexer.ErrorHandler(&Error{file: lexer.file, pos: lexer.tokenEnd, err: ErrTooManyErrors})
+		}
+
+		lexer.done = true
+	}
+}
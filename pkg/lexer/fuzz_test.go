@@ -0,0 +1,57 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"testing"
+
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// FuzzLex feeds arbitrary bytes through the lexer and drains the
+// token stream to EOF, asserting that no input can panic the lexer
+// or keep it from terminating. The seed corpus covers the known
+// tricky spots: byte order marks, illegal UTF-8, unterminated
+// literals, and malformed numbers and escapes.
+func FuzzLex(f *testing.F) {
+	seeds := []string{
+		"",
+		"let x = 42\nprint(x)\n",
+		"\uFEFFpackage main",
+		"a\n\uFEFFb\n",
+		"\xFF\xFE\xFD",
+		"ab\xC3x",
+		"\"unterminated",
+		"'x",
+		"'\\u{",
+		"\"\\uD800\" \"\\U00110000\"",
+		"\\escaped",
+		"\\\\",
+		"0x_1_.5p",
+		"1_000_000.5e+",
+		"0b2 0o9 09",
+		"#!shebang\n## doc\n# plain",
+		"a // b\n/// c",
+		"if x == 5 { x ??= y?.z }",
+	}
+
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, source []byte) {
+		lexer := LexBytes(source, IgnoreErrors, MaxErrors(0))
+		for tok := lexer.NextToken(); tok.Type != token.EOF; tok = lexer.NextToken() {
+		}
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"fmt"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// isIdentStart reports whether the given rune can start an
+// identifier.
+func isIdentStart(char rune) bool {
+	return unicode.IsLetter(char) || char == '_'
+}
+
+// isIdentPart reports whether the given rune can appear inside an
+// identifier. Following the UAX #31 identifier profile, combining
+// marks are identifier runes in continue positions only: scripts
+// like Devanagari spell ordinary words with them. isIdentStart keeps
+// rejecting them, so a mark cannot open an identifier.
+func isIdentPart(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_' ||
+		unicode.In(char, unicode.Mn, unicode.Mc)
+}
+
+// lexIdentifier lexes an identifier or keyword token.
+func (lexer *Lexer) lexIdentifier() {
+	lexer.consumeIdentifier()
+
+	if lexer.afterNumber {
+		lexer.afterNumber = false
+		lexer.raise(fmt.Errorf("%w: %q", ErrNumberSuffix, lexer.literal()))
+	}
+
+	// under the Normalize option, the literal is rewritten to NFC so
+	// precomposed and decomposed spellings of the same name compare
+	// equal. The token's positions still span the raw source text.
+	if lexer.normalize {
+		literal := lexer.literal()
+		if normalized := norm.NFC.String(literal); normalized != literal {
+			lexer.tokenLiteral.Reset()
+			lexer.tokenLiteral.WriteString(normalized)
+		}
+	}
+
+	if lexer.mixedScript {
+		lexer.checkMixedScript()
+	}
+
+	if lexer.foldCase {
+		lexer.emit(token.LookupFold(lexer.literal()))
+	} else {
+		lexer.emit(token.Lookup(lexer.literal()))
+	}
+}
+
+// confusableScripts are the scripts whose letters contain mutual
+// homoglyphs, like Latin a and Cyrillic а, making identifiers which
+// mix them a vector for spoofing.
+var confusableScripts = []*unicode.RangeTable{
+	unicode.Latin, unicode.Cyrillic, unicode.Greek,
+}
+
+// checkMixedScript raises ErrMixedScript when the identifier literal
+// accumulated so far draws letters from more than one confusable
+// script. The warning is non-fatal: the identifier is still emitted
+// exactly as written.
+func (lexer *Lexer) checkMixedScript() {
+	seen := 0
+	for _, char := range lexer.literal() {
+		for i, script := range confusableScripts {
+			if unicode.Is(script, char) {
+				seen |= 1 << i
+			}
+		}
+	}
+
+	// more than one bit set means more than one script
+	if seen&(seen-1) != 0 {
+		lexer.raise(ErrMixedScript)
+	}
+}
+
+// consumeIdentifier consumes a run of identifier runes.
+func (lexer *Lexer) consumeIdentifier() {
+	for isIdentPart(lexer.current) {
+		lexer.consume()
+	}
+}
+
+// lexEscapedIdentifier lexes an identifier escaped between
+// backslashes, like \func\, which is an identifier even when its
+// contents form a keyword. The literal includes the backslashes, so
+// the escaped form stays distinguishable from a plain identifier.
+//
+// The contents must form a valid identifier in their own right: at
+// least one rune, starting with a rune which can start an identifier.
+// Anything else is still consumed up to the closing backslash, but
+// raises an error.
+func (lexer *Lexer) lexEscapedIdentifier() {
+	lexer.consume() // opening backslash
+
+	valid := true
+	runes := 0
+	for lexer.current != '\\' {
+		if lexer.current == eof || lexer.current == '\n' {
+			lexer.raise(ErrUnclosedEscapedIdent)
+			lexer.emit(token.Identifier)
+			return
+		}
+
+		if runes == 0 && !isIdentStart(lexer.current) ||
+			runes > 0 && !isIdentPart(lexer.current) {
+			valid = false
+		}
+
+		runes++
+		lexer.consume()
+	}
+
+	lexer.consume() // closing backslash
+
+	switch {
+	case runes == 0:
+		lexer.raise(ErrEmptyEscapedIdent)
+	case !valid:
+		lexer.raise(ErrIllegalEscapedIdent)
+	}
+
+	lexer.emit(token.Identifier)
+}
@@ -0,0 +1,917 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lexer implements a tokenizer for krypton source code.
+//
+// The Lexer type tokenizes its source concurrently on a background
+// goroutine, while the Scanner type tokenizes its source inline, one
+// token per call. Both produce identical token streams on identical
+// sources.
+package lexer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/kryptonlang/krypton/pkg/file"
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// eof is the sentinel rune which represents the end of the source.
+const eof rune = -1
+
+// bom is the Unicode byte order mark, which is permitted only as the
+// very first rune of a source.
+const bom rune = 0xFEFF
+
+// tokenBufferSize is the capacity of the token stream channel. The
+// buffer lets the lexing goroutine run ahead of the consumer instead
+// of paying a synchronous handoff per token, which dominates lexing
+// time on large sources.
+const tokenBufferSize = 64
+
+// Lexer lexes a krypton source into a stream of tokens. The tokens
+// are lexed concurrently on a separate goroutine and are received,
+// one at a time, with NextToken.
+//
+// Callers must either drain the lexer to EOF or call Close, since
+// the lexing goroutine blocks until its current token is received
+// and is otherwise leaked.
+type Lexer struct {
+	// ErrorHandler is called with every error encountered while
+	// lexing, if it is non-nil.
+	ErrorHandler ErrorHandler
+
+	// Errors is the number of errors encountered while lexing.
+	Errors int
+
+	source *bufio.Reader // the source being lexed
+	file   *file.File    // the file being lexed, if lexing a file
+	buf    []byte        // in-memory source of a buffer-backed lexer, if any
+
+	current   rune // the rune currently being inspected
+	peeked    rune // the buffered rune after current, if hasPeeked
+	hasPeeked bool // whether peeked holds a buffered rune
+	readAny   bool // whether any rune has been read from the source
+	offset    int  // number of bytes consumed from the source
+
+	// byte offsets into buf used by a buffer-backed lexer to carve
+	// token literals out of the source without accumulating runes
+	literalStart  int // first byte of the literal being lexed
+	literalEnd    int // just past the last byte of that literal
+	currentOffset int // first byte of the current rune
+	peekedOffset  int // first byte of the peeked rune, if hasPeeked
+
+	tokenStream  chan token.Token // channel down which tokens are emitted
+	quit         chan struct{}    // closed by Close to stop the lexing goroutine
+	ctxDone      <-chan struct{}  // done channel of the context given to LexContext
+	pending      []token.Token    // emitted but unreturned tokens, in synchronous mode
+	tokenLiteral strings.Builder  // literal of the token being lexed
+	rawLiteral   []byte           // exact source bytes of the token, if trackSource
+	tokenStart   file.Pos         // position of the current token's first rune
+	tokenEnd     file.Pos         // position just past the current token's last rune
+	lastEnd      file.Pos         // end of the last token returned by NextToken
+	progress     uint64           // tokenEnd packed as line<<32|column, accessed atomically
+	unread       []token.Token    // tokens pushed back by Unread, in push order
+
+	sync        bool // whether the lexer is driven synchronously by a Scanner
+	insertSemi  bool // whether a semicolon should be inserted at a newline
+	closed      bool // whether the lexer has been torn down with Close
+	done        bool // whether the source has been fully lexed
+	foldCase    bool // whether keywords are matched case-insensitively
+	strictOctal bool // whether legacy octal literals are rejected
+	strictHexFl bool // whether hex floats require a 'p' exponent
+	numSuffix   bool // whether identifiers directly after numbers are rejected
+	skipBOM     bool // whether interior byte order marks are silently skipped
+	afterNumber bool // whether the previous token was a directly adjacent number
+	whitespace  bool // whether whitespace is emitted instead of discarded
+	normalize   bool // whether identifier literals are normalized to NFC
+	mixedScript bool // whether mixed-script identifiers are reported
+	trackSource bool // whether tokens retain their exact source bytes
+	blankLines  bool // whether blank lines emit BlankLine tokens
+	strictUTF8  bool // whether illegal utf-8 encodings abort lexing
+	indentation bool // whether Indent and Dedent tokens are emitted
+	atLineStart bool // whether the next token is the first on its line
+
+	indents []int // stack of active indentation widths, in columns
+
+	maxErrors int // number of errors after which lexing is abandoned
+	tabWidth  int // width used to advance the column over tabs
+
+	commentIntro string // introducer which opens a line comment
+
+	// commentHandler, if non-nil, receives Comment and DocComment
+	// tokens instead of the token stream
+	commentHandler func(token.Token)
+
+	// transform, if non-nil, is applied to every token before it is
+	// emitted, and may rewrite, remap, or drop it
+	transform func(token.Token) token.Token
+
+	// insertSemiAfter decides which token types demand a semicolon
+	// at the newline which follows them
+	insertSemiAfter func(token.Type) bool
+}
+
+// Lex creates a new Lexer which tokenizes the given source. The
+// handler, if non-nil, is called with every error encountered while
+// lexing. Lexing happens concurrently on a separate goroutine, and
+// the resulting tokens are received with NextToken.
+func Lex(source io.Reader, handler ErrorHandler, options ...Option) *Lexer {
+	lexer := newLexer(source, handler, options...)
+	lexer.tokenStream = make(chan token.Token, tokenBufferSize)
+	lexer.quit = make(chan struct{})
+
+	go lexer.lex()
+	return lexer
+}
+
+// LexString creates a new Lexer like Lex which tokenizes the given
+// string.
+func LexString(source string, handler ErrorHandler, options ...Option) *Lexer {
+	return Lex(strings.NewReader(source), handler, options...)
+}
+
+// LexBytes creates a new Lexer like Lex which tokenizes the given
+// byte slice. Unlike the streaming constructors, it decodes runes
+// directly from the slice and carves token literals out of it as
+// sub-slices, converted to string only when a token is emitted,
+// avoiding the per-rune reader calls and literal accumulation of the
+// reader-backed path. The tokens it produces are identical to Lex's.
+func LexBytes(source []byte, handler ErrorHandler, options ...Option) *Lexer {
+	options = append([]Option{withBuffer(source)}, options...)
+	return Lex(bytes.NewReader(nil), handler, options...)
+}
+
+// withBuffer is the internal option which puts a lexer into
+// buffer-backed mode, reading from the given slice instead of its
+// reader. As an option, it applies before the constructor primes the
+// lexer with the first rune.
+func withBuffer(source []byte) Option {
+	return func(lexer *Lexer) {
+		lexer.buf = source
+	}
+}
+
+// LexFile creates a new Lexer like Lex which tokenizes the given
+// file. Errors reported to the handler carry positions qualified
+// with the file's name, like "main.kr:3:5".
+func LexFile(source *file.File, handler ErrorHandler, options ...Option) *Lexer {
+	lexer := newLexer(source.Source(), handler, options...)
+	lexer.file = source
+	lexer.tokenStream = make(chan token.Token, tokenBufferSize)
+	lexer.quit = make(chan struct{})
+
+	go lexer.lex()
+	return lexer
+}
+
+// LexContext creates a new Lexer like Lex, whose lexing goroutine is
+// additionally torn down when the given context is cancelled. After
+// cancellation, NextToken returns EOF tokens once the tokens already
+// in flight have been received.
+func LexContext(ctx context.Context, source io.Reader, handler ErrorHandler, options ...Option) *Lexer {
+	lexer := newLexer(source, handler, options...)
+	lexer.tokenStream = make(chan token.Token, tokenBufferSize)
+	lexer.quit = make(chan struct{})
+	lexer.ctxDone = ctx.Done()
+
+	go lexer.lex()
+	return lexer
+}
+
+// newLexer creates a Lexer primed with the first rune of the given
+// source, without a token stream or a lexing goroutine.
+func newLexer(source io.Reader, handler ErrorHandler, options ...Option) *Lexer {
+	lexer := &Lexer{
+		ErrorHandler:    handler,
+		source:          bufio.NewReader(source),
+		insertSemiAfter: token.InsertSemiAfter,
+		tokenStart:      file.Origin,
+		tokenEnd:        file.Origin,
+		lastEnd:         file.Origin,
+		atLineStart:     true,
+		maxErrors:       defaultMaxErrors,
+		tabWidth:        1,
+		commentIntro:    "#",
+	}
+
+	for _, option := range options {
+		option(lexer)
+	}
+
+	lexer.storeProgress(file.Origin)
+	lexer.readRune()
+	return lexer
+}
+
+// NextToken returns the next token lexed from the source. Once the
+// source has been exhausted, or the lexer has been torn down with
+// Close, it returns EOF tokens positioned at the end of the last
+// token received, however many times it is called.
+func (lexer *Lexer) NextToken() token.Token {
+	if len(lexer.unread) > 0 {
+		tok := lexer.unread[len(lexer.unread)-1]
+		lexer.unread = lexer.unread[:len(lexer.unread)-1]
+		return tok
+	}
+
+	if lexer.closed {
+		return lexer.eofToken()
+	}
+
+	// a receive from the closed stream yields a zero Token, which
+	// must not escape to callers: it is detected with the comma-ok
+	// form and replaced by a well-formed EOF token. lastEnd is only
+	// touched from the consuming side, so tracking it here is safe
+	// even while the lexing goroutine is still running.
+	tok, ok := <-lexer.tokenStream
+	if !ok {
+		return lexer.eofToken()
+	}
+
+	// the explicit EOF token is emitted before the lexing goroutine
+	// finishes its per-token bookkeeping and closes the stream, so a
+	// consumer which acts on the EOF directly could race with those
+	// final writes. Draining the stream to its close first restores
+	// the guarantee Reset and the Errors field rely on: once EOF has
+	// been returned, the goroutine has finished with the lexer.
+	if tok.Type == token.EOF {
+		for range lexer.tokenStream {
+		}
+	}
+
+	lexer.lastEnd = tok.End
+	return tok
+}
+
+// Peek returns the next token without consuming it: the subsequent
+// NextToken call returns that same token. At EOF it behaves exactly
+// like NextToken, returning EOF tokens however often it is called.
+func (lexer *Lexer) Peek() token.Token {
+	tok := lexer.NextToken()
+	lexer.Unread(tok)
+	return tok
+}
+
+// Unread pushes the given token back onto the lexer, so NextToken
+// returns it before lexing any further. Tokens are pushed onto an
+// unbounded stack and returned in last-in-first-out order, letting a
+// parser unwind arbitrarily deep speculation.
+func (lexer *Lexer) Unread(tok token.Token) {
+	lexer.unread = append(lexer.unread, tok)
+}
+
+// Position returns the position in the source up to which the lexer
+// has advanced, for progress reporting on large sources. It is
+// updated once per emitted token and is safe to call concurrently
+// with the lexing goroutine and with NextToken; note that because
+// tokens are buffered, the lexer may run ahead of the tokens received
+// so far.
+func (lexer *Lexer) Position() file.Pos {
+	packed := atomic.LoadUint64(&lexer.progress)
+	return file.Pos{Line: int(packed >> 32), Column: int(packed & 0xFFFFFFFF)}
+}
+
+// storeProgress publishes the given position for Position to read
+// from other goroutines.
+func (lexer *Lexer) storeProgress(pos file.Pos) {
+	atomic.StoreUint64(&lexer.progress, uint64(pos.Line)<<32|uint64(pos.Column))
+}
+
+// eofToken synthesizes the EOF token returned by NextToken once no
+// further tokens can be received.
+func (lexer *Lexer) eofToken() token.Token {
+	return token.Token{Type: token.EOF, Pos: lexer.lastEnd, End: lexer.lastEnd}
+}
+
+// Reset re-initializes the lexer to lex the given source from the
+// beginning, reusing its read buffer instead of allocating a fresh
+// Lexer per source. All positions, counters, and pending state are
+// cleared, and a new lexing goroutine is started for lexers created
+// with Lex, LexFile, or LexContext.
+//
+// Reset must only be called once the previous token stream has been
+// drained to EOF or torn down with Close: resetting a lexer whose
+// goroutine is still lexing corrupts its state.
+func (lexer *Lexer) Reset(source io.Reader) {
+	lexer.Errors = 0
+	lexer.source.Reset(source)
+	lexer.file = nil
+	lexer.buf = nil
+
+	lexer.hasPeeked = false
+	lexer.readAny = false
+	lexer.offset = 0
+	lexer.currentOffset, lexer.peekedOffset = 0, 0
+	lexer.unread = lexer.unread[:0]
+
+	lexer.pending = lexer.pending[:0]
+	lexer.resetLiteral()
+	lexer.tokenStart = file.Origin
+	lexer.tokenEnd = file.Origin
+	lexer.lastEnd = file.Origin
+
+	lexer.insertSemi = false
+	lexer.afterNumber = false
+	lexer.closed = false
+	lexer.done = false
+
+	lexer.atLineStart = true
+	lexer.indents = lexer.indents[:0]
+
+	lexer.storeProgress(file.Origin)
+	lexer.readRune()
+
+	if !lexer.sync {
+		lexer.tokenStream = make(chan token.Token, tokenBufferSize)
+		lexer.quit = make(chan struct{})
+
+		go lexer.lex()
+	}
+}
+
+// Tokens returns an iterator over the tokens lexed from the source,
+// usable with range-over-func and assignable to iter.Seq[token.Token]
+// on Go versions which provide it. The iterator stops at the EOF
+// token without yielding it. Breaking out of the loop early closes
+// the lexer, so the lexing goroutine is never leaked.
+func (lexer *Lexer) Tokens() func(yield func(token.Token) bool) {
+	return func(yield func(token.Token) bool) {
+		for {
+			tok := lexer.NextToken()
+			if tok.Type == token.EOF {
+				return
+			}
+
+			if !yield(tok) {
+				lexer.Close()
+				return
+			}
+		}
+	}
+}
+
+// Close stops the lexing goroutine without draining the remaining
+// tokens. Every subsequent NextToken call returns an EOF token. It
+// is safe to call Close multiple times, and after the lexer has
+// already been drained to EOF.
+func (lexer *Lexer) Close() {
+	if !lexer.closed {
+		lexer.closed = true
+		close(lexer.quit)
+	}
+}
+
+// lex lexes tokens from the source and sends them down the token
+// stream, closing the stream once the source is exhausted.
+func (lexer *Lexer) lex() {
+	for !lexer.done {
+		lexer.next()
+	}
+
+	lexer.close()
+}
+
+// next lexes the next token from the source, along with any
+// automatically inserted semicolon which precedes it, and emits it.
+// It flags the lexer as done once the source is exhausted.
+func (lexer *Lexer) next() {
+	lexer.discardWhitespace()
+
+	if lexer.current == eof {
+		// open indentation levels are closed with Dedent tokens
+		// before the end of the source, like at any other dedent
+		for len(lexer.indents) > 0 {
+			lexer.indents = lexer.indents[:len(lexer.indents)-1]
+			lexer.emit(token.Dedent)
+		}
+
+		// an explicit EOF token is emitted so consumers receive the
+		// end-of-file position, rather than a zero token synthesized
+		// after the stream closes
+		lexer.done = true
+		lexer.emit(token.EOF)
+		return
+	}
+
+	switch current := lexer.current; {
+	case isIdentStart(current):
+		lexer.lexIdentifier()
+	case token.IsDigit(current, 10):
+		lexer.lexNumber()
+	case current == '.' && token.IsDigit(lexer.peek(), 10):
+		// a float with a leading decimal point, like .5; a dot not
+		// followed by a digit stays an operator, so a.b is still a
+		// field access
+		lexer.lexNumber()
+	case current == '"':
+		lexer.lexString()
+	case current == '\'':
+		lexer.lexRune()
+	case lexer.atComment():
+		lexer.lexComment()
+	case current == '\\':
+		lexer.lexEscapedIdentifier()
+	case token.IsOperator(string(current)):
+		lexer.lexOperator()
+	default:
+		lexer.lexIllegal()
+	}
+}
+
+// lexIllegal lexes a run of consecutive illegal runes into a single
+// Illegal token spanning the whole run, raising one error for the
+// span rather than one per rune.
+func (lexer *Lexer) lexIllegal() {
+	lexer.raise(ErrIllegalRune)
+
+	for lexer.current != eof && !lexer.startsToken(lexer.current) {
+		lexer.consume()
+	}
+
+	lexer.emit(token.Illegal)
+}
+
+// startsToken reports whether the given rune can start a token or is
+// whitespace, i.e. whether it is anything but an illegal rune.
+func (lexer *Lexer) startsToken(char rune) bool {
+	switch char {
+	case '"', '\'', '\\', ' ', '\t', '\r', '\n':
+		return true
+	default:
+		first, _ := utf8.DecodeRuneInString(lexer.commentIntro)
+		return char == first || isIdentStart(char) || token.IsDigit(char, 10) ||
+			token.IsOperator(string(char))
+	}
+}
+
+// lexOperator lexes an operator token. Operators are lexed greedily:
+// runes keep getting consumed while the literal built so far remains
+// a valid operator, which works because every prefix of a valid
+// operator is itself a valid operator.
+func (lexer *Lexer) lexOperator() {
+	// the dispatch in next guarantees the current rune is an operator
+	// by itself, so the first rune is consumed unconditionally: the
+	// literal can never end up empty, even at the end of the source,
+	// and the emitted type can never degrade to the zero value
+	lexer.consume()
+
+	for token.IsOperator(lexer.literal() + string(lexer.current)) {
+		lexer.consume()
+	}
+
+	// the prefix invariant guarantees the accumulated literal is a
+	// valid operator; should the table ever break it, the run is
+	// surfaced as an Illegal token with an error instead
+	tokenType := token.NewTokenType(lexer.literal())
+	if tokenType == token.Illegal {
+		lexer.raise(ErrIllegalRune)
+	}
+
+	lexer.emit(tokenType)
+}
+
+// discardWhitespace discards the whitespace preceding the next
+// token, inserting a semicolon at newlines when the previously
+// emitted token demands one. Under the KeepWhitespace option, the
+// whitespace is emitted as Whitespace tokens instead of discarded.
+func (lexer *Lexer) discardWhitespace() {
+	if lexer.whitespace {
+		lexer.lexWhitespace()
+		return
+	}
+
+	for {
+		switch lexer.current {
+		case '\n':
+			if lexer.insertSemi {
+				lexer.tokenLiteral.WriteByte('\n')
+				lexer.emit(token.Semicolon)
+			}
+
+			lexer.discard()
+			lexer.atLineStart = true
+
+			// under the BlankLines option, a newline directly
+			// following another emits a BlankLine token, which a
+			// REPL treats as an end-of-input signal
+			if lexer.blankLines && lexer.current == '\n' {
+				lexer.tokenLiteral.WriteByte('\n')
+				lexer.emit(token.BlankLine)
+			}
+		case '\r':
+			// a \r\n pair is a single logical line terminator:
+			// the \r is dropped without advancing the column, so
+			// the \n behaves exactly like a lone \n, inserting at
+			// most one semicolon
+			if lexer.peek() == '\n' {
+				lexer.readRune()
+			} else {
+				lexer.discard()
+			}
+		case ' ', '\t':
+			lexer.discard()
+		default:
+			// blank lines never reach this point, and comment-only
+			// lines are excluded by the introducer check, so neither
+			// affects the indentation stack
+			if lexer.indentation && lexer.atLineStart &&
+				!lexer.atComment() && lexer.current != eof {
+				lexer.applyIndentation()
+			}
+
+			return
+		}
+	}
+}
+
+// applyIndentation compares the leading whitespace of the line being
+// started against the indentation stack, emitting an Indent token
+// when the line is indented past the enclosing level and a Dedent
+// token for every level it closes. A dedent which lands between two
+// levels of the stack raises ErrIndentMismatch.
+func (lexer *Lexer) applyIndentation() {
+	lexer.atLineStart = false
+
+	// columns honor the TabWidth option, so mixing tabs and spaces
+	// behaves consistently as long as the width matches the source's
+	// conventions
+	width := lexer.tokenEnd.Column - 1
+
+	enclosing := 0
+	if len(lexer.indents) > 0 {
+		enclosing = lexer.indents[len(lexer.indents)-1]
+	}
+
+	switch {
+	case width > enclosing:
+		lexer.indents = append(lexer.indents, width)
+		lexer.emit(token.Indent)
+
+	case width < enclosing:
+		for len(lexer.indents) > 0 && lexer.indents[len(lexer.indents)-1] > width {
+			lexer.indents = lexer.indents[:len(lexer.indents)-1]
+			lexer.emit(token.Dedent)
+		}
+
+		remaining := 0
+		if len(lexer.indents) > 0 {
+			remaining = lexer.indents[len(lexer.indents)-1]
+		}
+
+		if remaining != width {
+			lexer.raise(ErrIndentMismatch)
+		}
+	}
+}
+
+// lexWhitespace lexes the whitespace preceding the next token into
+// Whitespace tokens, so concatenating every literal in the stream
+// reconstructs the source byte for byte. Inserted semicolons still
+// claim the newline which triggered them as their literal, and the
+// newline appears in no Whitespace token.
+func (lexer *Lexer) lexWhitespace() {
+	for {
+		switch lexer.current {
+		case '\n':
+			if lexer.insertSemi {
+				lexer.flushWhitespace()
+
+				lexer.tokenLiteral.WriteByte('\n')
+				lexer.emit(token.Semicolon)
+				lexer.discard()
+				continue
+			}
+
+			lexer.consume()
+		case ' ', '\t', '\r':
+			lexer.consume()
+		default:
+			lexer.flushWhitespace()
+			return
+		}
+	}
+}
+
+// flushWhitespace emits the whitespace run accumulated so far, if
+// any, as a Whitespace token. Like comments, whitespace does not
+// affect semicolon insertion.
+func (lexer *Lexer) flushWhitespace() {
+	if lexer.literalLen() == 0 {
+		return
+	}
+
+	insertSemi := lexer.insertSemi
+	lexer.emit(token.Whitespace)
+	lexer.insertSemi = insertSemi
+}
+
+// emit sends a token of the given type down the token stream, built
+// from the literal and positions accumulated since the last token.
+func (lexer *Lexer) emit(tokenType token.Type) {
+	lexer.insertSemi = lexer.insertSemiAfter(tokenType)
+	lexer.storeProgress(lexer.tokenEnd)
+
+	tok := token.Token{
+		Type:    tokenType,
+		Literal: lexer.literal(),
+		Pos:     lexer.tokenStart,
+		End:     lexer.tokenEnd,
+	}
+
+	if lexer.trackSource && len(lexer.rawLiteral) > 0 {
+		tok = tok.WithSource(append([]byte(nil), lexer.rawLiteral...))
+		lexer.rawLiteral = lexer.rawLiteral[:0]
+	}
+
+	if lexer.transform != nil {
+		tok = lexer.transform(tok)
+
+		if tok.Type == Drop {
+			lexer.resetLiteral()
+			lexer.tokenStart = lexer.tokenEnd
+			return
+		}
+	}
+
+	if lexer.commentHandler != nil &&
+		(tok.Type == token.Comment || tok.Type == token.DocComment) {
+		lexer.commentHandler(tok)
+
+		lexer.resetLiteral()
+		lexer.tokenStart = lexer.tokenEnd
+		return
+	}
+
+	if lexer.sync {
+		lexer.pending = append(lexer.pending, tok)
+	} else {
+		select {
+		case lexer.tokenStream <- tok:
+		case <-lexer.quit:
+			// the lexer has been closed: stop lexing
+			lexer.done = true
+		case <-lexer.ctxDone:
+			// the lexer's context has been cancelled: stop lexing
+			lexer.done = true
+		}
+	}
+
+	lexer.resetLiteral()
+	lexer.tokenStart = lexer.tokenEnd
+}
+
+// resetLiteral clears the literal accumulated for the token being
+// lexed, in the builder and, for buffer-backed lexers, the sub-slice
+// offsets alike.
+func (lexer *Lexer) resetLiteral() {
+	lexer.tokenLiteral.Reset()
+	lexer.literalStart, lexer.literalEnd = 0, 0
+}
+
+// consume appends the current rune to the literal of the token being
+// lexed and advances the lexer to the next rune.
+func (lexer *Lexer) consume() {
+	switch lexer.current {
+	case '\n':
+		lexer.tokenEnd.NextLine()
+	case '\t':
+		lexer.tokenEnd.NextTab(lexer.tabWidth)
+	default:
+		lexer.tokenEnd.NextCharacter()
+	}
+
+	if lexer.trackSource {
+		lexer.rawLiteral = utf8.AppendRune(lexer.rawLiteral, lexer.current)
+	}
+
+	switch {
+	case lexer.buf == nil || lexer.tokenLiteral.Len() > 0:
+		lexer.tokenLiteral.WriteRune(lexer.current)
+
+	case lexer.literalStart == lexer.literalEnd:
+		// a fresh literal starts at the current rune
+		lexer.literalStart = lexer.currentOffset
+		lexer.literalEnd = lexer.currentOffset + utf8.RuneLen(lexer.current)
+
+	case lexer.literalEnd == lexer.currentOffset:
+		lexer.literalEnd += utf8.RuneLen(lexer.current)
+
+	default:
+		// a skipped rune, like an interior byte order mark, left a
+		// gap inside the literal: fall back to building this one in
+		// the literal builder, which keeps the gap out
+		lexer.tokenLiteral.Write(lexer.buf[lexer.literalStart:lexer.literalEnd])
+		lexer.tokenLiteral.WriteRune(lexer.current)
+	}
+
+	lexer.readRune()
+}
+
+// literal returns the literal accumulated so far for the token being
+// lexed, wherever it lives: the literal builder takes precedence,
+// with a buffer-backed lexer otherwise converting its sub-slice of
+// the source.
+func (lexer *Lexer) literal() string {
+	if lexer.buf != nil && lexer.tokenLiteral.Len() == 0 {
+		return string(lexer.buf[lexer.literalStart:lexer.literalEnd])
+	}
+
+	return lexer.tokenLiteral.String()
+}
+
+// literalLen returns the length in bytes of the literal accumulated
+// so far, without converting it to a string.
+func (lexer *Lexer) literalLen() int {
+	if lexer.buf != nil && lexer.tokenLiteral.Len() == 0 {
+		return lexer.literalEnd - lexer.literalStart
+	}
+
+	return lexer.tokenLiteral.Len()
+}
+
+// discard drops the current rune, starting a fresh token at the next
+// rune, and advances the lexer.
+func (lexer *Lexer) discard() {
+	switch lexer.current {
+	case '\n':
+		lexer.tokenEnd.NextLine()
+	case '\t':
+		lexer.tokenEnd.NextTab(lexer.tabWidth)
+	default:
+		lexer.tokenEnd.NextCharacter()
+	}
+
+	lexer.resetLiteral()
+	lexer.rawLiteral = lexer.rawLiteral[:0]
+	lexer.tokenStart = lexer.tokenEnd
+	lexer.readRune()
+}
+
+// peek returns the rune directly after the current one without
+// consuming it. The peeked rune is buffered and handed out by the
+// next readRune call, so positions are not double-counted and the
+// utf-8, BOM, and EOF handling of readRune applies as usual.
+func (lexer *Lexer) peek() rune {
+	if !lexer.hasPeeked {
+		current, currentOffset := lexer.current, lexer.currentOffset
+		lexer.readRune()
+
+		lexer.peeked, lexer.peekedOffset = lexer.current, lexer.currentOffset
+		lexer.hasPeeked = true
+		lexer.current, lexer.currentOffset = current, currentOffset
+	}
+
+	return lexer.peeked
+}
+
+// readRune reads the next rune from the source into lexer.current,
+// raising errors for illegal utf-8 encodings and misplaced byte
+// order marks. At the end of the source, lexer.current is set to eof.
+func (lexer *Lexer) readRune() {
+	if lexer.hasPeeked {
+		lexer.hasPeeked = false
+		lexer.current = lexer.peeked
+		lexer.currentOffset = lexer.peekedOffset
+		return
+	}
+
+	if lexer.buf != nil {
+		lexer.readBufferRune()
+		return
+	}
+
+	for {
+		// raise may abandon lexing from inside this loop when
+		// the error limit is reached, as on binary garbage where
+		// every byte is an illegal encoding
+		if lexer.done {
+			lexer.current = eof
+			return
+		}
+
+		char, size, err := lexer.source.ReadRune()
+		first := !lexer.readAny
+		lexer.readAny = true
+
+		offset := lexer.offset
+		lexer.offset += size
+
+		switch {
+		case err == io.EOF:
+			lexer.current = eof
+			return
+
+		case err != nil:
+			lexer.raise(err)
+			lexer.current = eof
+			return
+
+		case char == utf8.RuneError && size == 1:
+			// ReadRune consumes exactly one byte when it reports an
+			// illegal encoding, so retrying always makes progress,
+			// even on arbitrarily long runs of illegal bytes. The
+			// byte itself is recovered for the diagnostic by
+			// unreading and re-reading it.
+			lexer.source.UnreadRune()
+			badByte, _ := lexer.source.ReadByte()
+
+			lexer.raise(fmt.Errorf("%w: byte %#02x at offset %d",
+				ErrIllegalUTF8, badByte, offset))
+
+			// under StrictUTF8, an illegal encoding is fatal: the
+			// rest of the source is abandoned instead of lexing a
+			// garbage token stream out of binary input
+			if lexer.strictUTF8 {
+				lexer.done = true
+				lexer.current = eof
+				return
+			}
+
+			continue
+
+		case char == bom:
+			if !first && !lexer.skipBOM {
+				lexer.raise(ErrIllegalBOM)
+			}
+
+			// byte order marks never reach the token stream
+			continue
+
+		default:
+			lexer.current = char
+			return
+		}
+	}
+}
+
+// readBufferRune is readRune's buffer-backed counterpart: it decodes
+// the next rune directly from the in-memory source, with the same
+// utf-8, BOM, and end-of-source handling as the streaming path, and
+// records the rune's byte offset so literals can be carved out of
+// the buffer.
+func (lexer *Lexer) readBufferRune() {
+	for {
+		if lexer.done || lexer.offset >= len(lexer.buf) {
+			lexer.current = eof
+			return
+		}
+
+		char, size := utf8.DecodeRune(lexer.buf[lexer.offset:])
+		first := !lexer.readAny
+		lexer.readAny = true
+
+		offset := lexer.offset
+		lexer.offset += size
+
+		switch {
+		case char == utf8.RuneError && size == 1:
+			lexer.raise(fmt.Errorf("%w: byte %#02x at offset %d",
+				ErrIllegalUTF8, lexer.buf[offset], offset))
+
+			if lexer.strictUTF8 {
+				lexer.done = true
+				lexer.current = eof
+				return
+			}
+
+			continue
+
+		case char == bom:
+			if !first && !lexer.skipBOM {
+				lexer.raise(ErrIllegalBOM)
+			}
+
+			// byte order marks never reach the token stream
+			continue
+
+		default:
+			lexer.current = char
+			lexer.currentOffset = offset
+			return
+		}
+	}
+}
+
+// close closes the lexer's token stream. Receiving from the closed
+// stream drains the tokens still in its buffer and then yields zero
+// Tokens, whose type is EOF.
+func (lexer *Lexer) close() {
+	close(lexer.tokenStream)
+}
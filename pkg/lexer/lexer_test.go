@@ -0,0 +1,1718 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kryptonlang/krypton/pkg/file"
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// drain collects every token produced by next, up to and including
+// the first EOF token.
+func drain(next func() token.Token) []token.Token {
+	var tokens []token.Token
+	for {
+		tok := next()
+		tokens = append(tokens, tok)
+
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// expectTokens drains the given token source and asserts that the
+// types and literals of the produced tokens match the expected ones.
+func expectTokens(t *testing.T, next func() token.Token, expected []token.Token) {
+	t.Helper()
+
+	tokens := drain(next)
+	if len(tokens) != len(expected) {
+		t.Fatalf("lexed %d tokens, expected %d: %v", len(tokens), len(expected), tokens)
+	}
+
+	for i, tok := range tokens {
+		if tok.Type != expected[i].Type || tok.Literal != expected[i].Literal {
+			t.Errorf(
+				"token %d: got %v %q, expected %v %q",
+				i, tok.Type, tok.Literal, expected[i].Type, expected[i].Literal,
+			)
+		}
+	}
+}
+
+func TestLex(t *testing.T) {
+	source := "let answer = 42\n" +
+		"if answer >= 10 {\n" +
+		"\tprint(\"big\\n\")\t# report\n" +
+		"}\n"
+
+	lexer := Lex(strings.NewReader(source), func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	})
+
+	expectTokens(t, lexer.NextToken, []token.Token{
+		{Type: token.Let, Literal: "let"},
+		{Type: token.Identifier, Literal: "answer"},
+		{Type: token.Equal, Literal: "="},
+		{Type: token.Int, Literal: "42"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.If, Literal: "if"},
+		{Type: token.Identifier, Literal: "answer"},
+		{Type: token.GreaterEqual, Literal: ">="},
+		{Type: token.Int, Literal: "10"},
+		{Type: token.LeftBrace, Literal: "{"},
+		{Type: token.Identifier, Literal: "print"},
+		{Type: token.LeftParen, Literal: "("},
+		{Type: token.String, Literal: `"big\n"`},
+		{Type: token.RightParen, Literal: ")"},
+		{Type: token.Comment, Literal: "# report"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.RightBrace, Literal: "}"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.EOF},
+	})
+}
+
+// TestOperators lexes snippets containing operators and checks the
+// resulting token types, which exercises lexOperator's greedy
+// longest-match behavior.
+func TestOperators(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected []token.Type
+	}{
+		{"a && b || c", []token.Type{
+			token.Identifier, token.AmpAmp, token.Identifier,
+			token.BarBar, token.Identifier, token.EOF,
+		}},
+		{"a & b | c", []token.Type{
+			token.Identifier, token.Amp, token.Identifier,
+			token.Bar, token.Identifier, token.EOF,
+		}},
+		{"a++", []token.Type{token.Identifier, token.PlusPlus, token.EOF}},
+		{"a--", []token.Type{token.Identifier, token.MinusMinus, token.EOF}},
+		{"a += 1", []token.Type{token.Identifier, token.PlusEqual, token.Int, token.EOF}},
+		{"a -= 1", []token.Type{token.Identifier, token.MinusEqual, token.Int, token.EOF}},
+		{"a ** b", []token.Type{token.Identifier, token.StarStar, token.Identifier, token.EOF}},
+		{"x **= 2", []token.Type{token.Identifier, token.StarStarEqual, token.Int, token.EOF}},
+		{"a * b", []token.Type{token.Identifier, token.Star, token.Identifier, token.EOF}},
+		{"x -> x + 1", []token.Type{
+			token.Identifier, token.Arrow, token.Identifier,
+			token.Plus, token.Int, token.EOF,
+		}},
+		{"a --> b", []token.Type{
+			token.Identifier, token.MinusMinus, token.Greater,
+			token.Identifier, token.EOF,
+		}},
+		{"a ->= b", []token.Type{
+			token.Identifier, token.Arrow, token.Equal,
+			token.Identifier, token.EOF,
+		}},
+		{"a .. b", []token.Type{token.Identifier, token.DotDot, token.Identifier, token.EOF}},
+		{"a ... b", []token.Type{token.Identifier, token.DotDotDot, token.Identifier, token.EOF}},
+		{"a.b", []token.Type{token.Identifier, token.Period, token.Identifier, token.EOF}},
+		{"1..5", []token.Type{token.Int, token.DotDot, token.Int, token.EOF}},
+		{"1...5", []token.Type{token.Int, token.DotDotDot, token.Int, token.EOF}},
+		{"arr[1..]", []token.Type{
+			token.Identifier, token.LeftBracket, token.Int,
+			token.DotDot, token.RightBracket, token.EOF,
+		}},
+		{"1.5", []token.Type{token.Float, token.EOF}},
+		{"a ?? b", []token.Type{
+			token.Identifier, token.QuestionQuestion, token.Identifier, token.EOF,
+		}},
+		{"a ??= b", []token.Type{
+			token.Identifier, token.QuestionQuestionEqual, token.Identifier, token.EOF,
+		}},
+		{"a &&= b", []token.Type{
+			token.Identifier, token.AmpAmpEqual, token.Identifier, token.EOF,
+		}},
+		{"a ||= b", []token.Type{
+			token.Identifier, token.BarBarEqual, token.Identifier, token.EOF,
+		}},
+		{"data |> filter |> map", []token.Type{
+			token.Identifier, token.BarGreater, token.Identifier,
+			token.BarGreater, token.Identifier, token.EOF,
+		}},
+		{"a?.b", []token.Type{
+			token.Identifier, token.QuestionPeriod, token.Identifier, token.EOF,
+		}},
+		{"a?.b ?? c", []token.Type{
+			token.Identifier, token.QuestionPeriod, token.Identifier,
+			token.QuestionQuestion, token.Identifier, token.EOF,
+		}},
+		{"@deprecated func foo()", []token.Type{
+			token.At, token.Identifier, token.Func, token.Identifier,
+			token.LeftParen, token.RightParen, token.EOF,
+		}},
+		{"a ? b : c", []token.Type{
+			token.Identifier, token.Question, token.Identifier,
+			token.Colon, token.Identifier, token.EOF,
+		}},
+		{"x > 0 ? x : -x", []token.Type{
+			token.Identifier, token.Greater, token.Int,
+			token.Question, token.Identifier, token.Colon,
+			token.Minus, token.Identifier, token.EOF,
+		}},
+		{"i++\nj", []token.Type{
+			// semicolon insertion must fire after a postfix ++
+			token.Identifier, token.PlusPlus, token.Semicolon,
+			token.Identifier, token.EOF,
+		}},
+	}
+
+	for _, c := range cases {
+		scanner := NewScanner(strings.NewReader(c.source), IgnoreErrors)
+		tokens := drain(scanner.NextToken)
+
+		if len(tokens) != len(c.expected) {
+			t.Errorf("%q: lexed %d tokens, expected %d", c.source, len(tokens), len(c.expected))
+			continue
+		}
+
+		for i, tok := range tokens {
+			if tok.Type != c.expected[i] {
+				t.Errorf("%q: token %d: got %v, expected %v", c.source, i, tok.Type, c.expected[i])
+			}
+		}
+	}
+}
+
+// TestKeywords lexes snippets containing keywords and checks the
+// resulting token types, including any automatically inserted
+// semicolons.
+func TestKeywords(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected []token.Type
+	}{
+		{"let x = nil\nreturn", []token.Type{
+			token.Let, token.Identifier, token.Equal, token.Nil,
+			token.Semicolon, token.Return, token.EOF,
+		}},
+		{"while cond {", []token.Type{
+			token.While, token.Identifier, token.LeftBrace, token.EOF,
+		}},
+		{"switch x { case 1: }", []token.Type{
+			token.Switch, token.Identifier, token.LeftBrace,
+			token.Case, token.Int, token.Colon, token.RightBrace,
+			token.EOF,
+		}},
+		{"match x { default: }", []token.Type{
+			token.Match, token.Identifier, token.LeftBrace,
+			token.Default, token.Colon, token.RightBrace, token.EOF,
+		}},
+		{"package main\nimport \"fmt\"", []token.Type{
+			token.Package, token.Identifier, token.Semicolon,
+			token.Import, token.String, token.EOF,
+		}},
+		{"module core", []token.Type{
+			token.Module, token.Identifier, token.EOF,
+		}},
+		{"for k in map", []token.Type{
+			token.For, token.Identifier, token.In, token.Identifier,
+			token.EOF,
+		}},
+		{"v as Float", []token.Type{
+			token.Identifier, token.As, token.Identifier, token.EOF,
+		}},
+	}
+
+	for _, c := range cases {
+		scanner := NewScanner(strings.NewReader(c.source), IgnoreErrors)
+		tokens := drain(scanner.NextToken)
+
+		if len(tokens) != len(c.expected) {
+			t.Errorf("%q: lexed %d tokens, expected %d", c.source, len(tokens), len(c.expected))
+			continue
+		}
+
+		for i, tok := range tokens {
+			if tok.Type != c.expected[i] {
+				t.Errorf("%q: token %d: got %v, expected %v", c.source, i, tok.Type, c.expected[i])
+			}
+		}
+	}
+}
+
+// TestFoldCase checks that the FoldCase option matches keywords
+// case-insensitively, and that the default lexer does not.
+func TestFoldCase(t *testing.T) {
+	source := "IF x { Return }\nİf"
+	expected := []token.Type{
+		token.If, token.Identifier, token.LeftBrace, token.Return,
+		token.RightBrace, token.Semicolon, token.Identifier,
+		token.EOF,
+	}
+
+	scanner := NewScanner(strings.NewReader(source), IgnoreErrors, FoldCase())
+	tokens := drain(scanner.NextToken)
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("lexed %d tokens, expected %d", len(tokens), len(expected))
+	}
+
+	for i, tok := range tokens {
+		if tok.Type != expected[i] {
+			t.Errorf("token %d: got %v, expected %v", i, tok.Type, expected[i])
+		}
+	}
+
+	scanner = NewScanner(strings.NewReader("IF"), IgnoreErrors)
+	if tok := scanner.NextToken(); tok.Type != token.Identifier {
+		t.Errorf("default lexer lexed IF as %v, expected IDENT", tok.Type)
+	}
+}
+
+func TestTokenizeAll(t *testing.T) {
+	tokens, errors := TokenizeAll(strings.NewReader("let x = $ 1"))
+
+	expected := []token.Type{
+		token.Let, token.Identifier, token.Equal, token.Illegal,
+		token.Int, token.EOF,
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("lexed %d tokens, expected %d", len(tokens), len(expected))
+	}
+
+	for i, tok := range tokens {
+		if tok.Type != expected[i] {
+			t.Errorf("token %d: got %v, expected %v", i, tok.Type, expected[i])
+		}
+	}
+
+	if len(errors) != 1 {
+		t.Errorf("collected %d errors, expected 1", len(errors))
+	}
+}
+
+func TestPeek(t *testing.T) {
+	lexer := newLexer(strings.NewReader("ab"), IgnoreErrors)
+
+	if lexer.current != 'a' {
+		t.Fatalf("current is %q, expected 'a'", lexer.current)
+	}
+
+	// peeking is idempotent and does not move current
+	for i := 0; i < 2; i++ {
+		if char := lexer.peek(); char != 'b' {
+			t.Fatalf("peek returned %q, expected 'b'", char)
+		}
+	}
+
+	if lexer.current != 'a' {
+		t.Fatalf("peek moved current to %q", lexer.current)
+	}
+
+	lexer.readRune()
+	if lexer.current != 'b' {
+		t.Fatalf("readRune after peek read %q, expected 'b'", lexer.current)
+	}
+
+	if char := lexer.peek(); char != eof {
+		t.Fatalf("peek at end of source returned %q, expected eof", char)
+	}
+}
+
+func TestStringLineContinuation(t *testing.T) {
+	errors := 0
+	scanner := NewScanner(strings.NewReader("\"ab\\\ncd\""), func(error) {
+		errors++
+	})
+
+	tok := scanner.NextToken()
+	if tok.Type != token.String || tok.Literal != "\"ab\\\ncd\"" {
+		t.Errorf("lexed %v %q", tok.Type, tok.Literal)
+	}
+
+	if errors != 0 {
+		t.Errorf("lexed with %d errors, expected none", errors)
+	}
+
+	// a genuine newline is still an unterminated string
+	errors = 0
+	scanner = NewScanner(strings.NewReader("\"ab\ncd\""), func(error) {
+		errors++
+	})
+	scanner.NextToken()
+
+	if errors != 1 {
+		t.Errorf("lexed with %d errors, expected 1", errors)
+	}
+}
+
+func TestOctalEscapes(t *testing.T) {
+	cases := []struct {
+		source string
+		errors int
+	}{
+		{`"\101\102"`, 0},
+		{`'\101'`, 0},
+		{`"\777"`, 1}, // does not fit in a byte
+		{`"\12"`, 1},  // too few digits
+	}
+
+	for _, c := range cases {
+		errors := 0
+		scanner := NewScanner(strings.NewReader(c.source), func(error) {
+			errors++
+		})
+		scanner.NextToken()
+
+		if errors != c.errors {
+			t.Errorf("%q: lexed with %d errors, expected %d", c.source, errors, c.errors)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	// identifiers in scripts which spell words with combining marks:
+	// Devanagari vowel signs and viramas are Mn/Mc runes
+	sources := []string{"नमस्ते", "क्षेत्र", "combining_é", "переменная", "変数"}
+
+	for _, source := range sources {
+		scanner := NewScanner(strings.NewReader(source), IgnoreErrors)
+
+		if tok := scanner.NextToken(); tok.Type != token.Identifier || tok.Literal != source {
+			t.Errorf("%q: lexed %v, expected a single identifier", source, tok)
+		}
+	}
+
+	// a combining mark cannot start an identifier
+	var list ErrorList
+	scanner := NewScanner(strings.NewReader("ाx"), list.Add)
+
+	if tok := scanner.NextToken(); tok.Type != token.Illegal {
+		t.Errorf("leading mark: lexed %v, expected an illegal token", tok)
+	}
+}
+
+func TestPeekToken(t *testing.T) {
+	lexer := LexString("a b", IgnoreErrors)
+
+	if tok := lexer.Peek(); tok.Type != token.Identifier || tok.Literal != "a" {
+		t.Errorf("peeked %v, expected identifier a", tok)
+	}
+
+	// a second peek returns the same token, as does NextToken
+	if tok := lexer.Peek(); tok.Literal != "a" {
+		t.Errorf("second peek returned %v, expected identifier a", tok)
+	}
+
+	if tok := lexer.NextToken(); tok.Literal != "a" {
+		t.Errorf("NextToken after peek returned %v, expected identifier a", tok)
+	}
+
+	if tok := lexer.NextToken(); tok.Literal != "b" {
+		t.Errorf("lexed %v, expected identifier b", tok)
+	}
+
+	if tok := lexer.NextToken(); tok.Type != token.EOF {
+		t.Errorf("lexed %v, expected EOF", tok)
+	}
+
+	for i := 0; i < 3; i++ {
+		if tok := lexer.Peek(); tok.Type != token.EOF {
+			t.Errorf("peek %d past EOF returned %v, expected EOF", i, tok)
+		}
+	}
+}
+
+// TestIllegalUTF8Progress feeds the lexer a long run of illegal
+// bytes, which must terminate with one error per byte: readRune's
+// retry loop advances the reader exactly one byte per illegal
+// encoding, so it can never spin in place.
+func TestIllegalUTF8Progress(t *testing.T) {
+	garbage := strings.Repeat("\xFF", 4096)
+
+	tokens, errs := TokenizeAll(strings.NewReader(garbage+"ok"), MaxErrors(0))
+
+	if len(errs) != 4096 {
+		t.Errorf("raised %d errors, expected one per illegal byte", len(errs))
+	}
+
+	if len(tokens) != 2 || tokens[0].Literal != "ok" {
+		t.Errorf("lexed %v, expected the trailing identifier and EOF", tokens)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if errs := Validate(strings.NewReader("let x = 1 + 2\n")); len(errs) != 0 {
+		t.Errorf("clean source reported %v, expected no errors", errs)
+	}
+
+	errs := Validate(strings.NewReader("\"unterminated\nlet $ = 1"))
+	if len(errs) != 2 {
+		t.Errorf("dirty source reported %v, expected 2 errors", errs)
+	}
+}
+
+func TestTokenizeSet(t *testing.T) {
+	set := file.NewFileSet()
+	set.Add("a.kr", "let x = 1\n")
+	set.Add("b.kr", "let $ = 2\n")
+
+	tokens, errs := TokenizeSet(set)
+	if len(tokens) != 2 {
+		t.Fatalf("lexed %d files, expected 2", len(tokens))
+	}
+
+	// positions restart for every file in the set
+	for i, fileTokens := range tokens {
+		first := fileTokens[0]
+		if first.Type != token.Let || (first.Pos != file.Pos{Line: 1, Column: 1}) {
+			t.Errorf("file %d starts with %v at %v, expected let at 1:1", i, first.Type, first.Pos)
+		}
+	}
+
+	// the error in b.kr resolves against b.kr's name
+	if len(errs) != 1 {
+		t.Fatalf("raised %v, expected a single error", errs)
+	}
+
+	if message := errs[0].Error(); !strings.HasPrefix(message, "b.kr:1:5") {
+		t.Errorf("error %q is not qualified with b.kr:1:5", message)
+	}
+}
+
+func TestLexerPosition(t *testing.T) {
+	lexer := LexString("let x = 1\nlet y = 2\n", nil)
+
+	// the lexer may have run ahead of the tokens received so far, but
+	// never behind them
+	tok := lexer.NextToken()
+	if pos := lexer.Position(); pos.Line < tok.End.Line ||
+		pos.Line == tok.End.Line && pos.Column < tok.End.Column {
+		t.Errorf("Position() = %v, behind the received token's end %v", pos, tok.End)
+	}
+
+	drain(lexer.NextToken)
+
+	if pos := lexer.Position(); (pos != file.Pos{Line: 3, Column: 1}) {
+		t.Errorf("Position() after draining = %v, expected 3:1", pos)
+	}
+}
+
+func TestIllegalUTF8Message(t *testing.T) {
+	var list ErrorList
+	scanner := NewScanner(strings.NewReader("ab\xC3x"), list.Add)
+	drain(scanner.NextToken)
+
+	if len(list) != 1 {
+		t.Fatalf("raised %v, expected a single error", list)
+	}
+
+	if !errors.Is(list[0], ErrIllegalUTF8) {
+		t.Errorf("raised %v, expected it to wrap ErrIllegalUTF8", list[0])
+	}
+
+	if message := list[0].Error(); !strings.Contains(message, "byte 0xc3 at offset 2") {
+		t.Errorf("message %q does not name the offending byte and offset", message)
+	}
+}
+
+func TestStrictUTF8(t *testing.T) {
+	var list ErrorList
+	scanner := NewScanner(strings.NewReader("a\xFF\xFEb"), list.Add, StrictUTF8())
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: token.Identifier, Literal: "a"},
+		{Type: token.EOF},
+	})
+
+	if len(list) != 1 || !errors.Is(list[0], ErrIllegalUTF8) {
+		t.Errorf("raised %v, expected a single fatal ErrIllegalUTF8", list)
+	}
+}
+
+// awaitKeyword is registered once per process: RegisterKeyword
+// panics on duplicates by design, which would trip the test when the
+// suite runs with -count greater than one.
+var awaitKeyword = token.RegisterKeyword("await")
+
+func TestInsertSemiAfterOption(t *testing.T) {
+	await := awaitKeyword
+
+	// registered keywords do not trigger insertion by default
+	scanner := NewScanner(strings.NewReader("await\nx"), IgnoreErrors)
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: await, Literal: "await"},
+		{Type: token.Identifier, Literal: "x"},
+		{Type: token.EOF},
+	})
+
+	scanner = NewScanner(strings.NewReader("await\nx"), IgnoreErrors,
+		InsertSemiAfter(func(tokenType token.Type) bool {
+			return tokenType == await || token.InsertSemiAfter(tokenType)
+		}))
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: await, Literal: "await"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Identifier, Literal: "x"},
+		{Type: token.EOF},
+	})
+}
+
+func TestBlankLines(t *testing.T) {
+	// a single newline inserts at most a semicolon; only a newline
+	// directly following another is a blank line
+	scanner := NewScanner(strings.NewReader("a\nb\n\nc\n\n\n"), IgnoreErrors, BlankLines())
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: token.Identifier, Literal: "a"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Identifier, Literal: "b"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.BlankLine, Literal: "\n"},
+		{Type: token.Identifier, Literal: "c"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.BlankLine, Literal: "\n"},
+		{Type: token.BlankLine, Literal: "\n"},
+		{Type: token.EOF},
+	})
+}
+
+func TestWithSource(t *testing.T) {
+	// "é" written as 'e' followed by the U+0301 combining acute:
+	// Normalize rewrites the literal, but not the source bytes
+	decomposed := "café"
+
+	scanner := NewScanner(strings.NewReader(decomposed), IgnoreErrors,
+		Normalize(), WithSource())
+
+	tok := scanner.NextToken()
+	if tok.Literal == decomposed {
+		t.Errorf("literal %q was not normalized", tok.Literal)
+	}
+
+	if string(tok.Source()) != decomposed {
+		t.Errorf("source bytes %q, expected the raw %q", tok.Source(), decomposed)
+	}
+
+	// without the option, no source bytes are retained
+	scanner = NewScanner(strings.NewReader(decomposed), IgnoreErrors)
+	if tok := scanner.NextToken(); tok.Source() != nil {
+		t.Errorf("source bytes %q retained without the option", tok.Source())
+	}
+}
+
+func TestClone(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("a\nb"), IgnoreErrors)
+
+	if tok := scanner.NextToken(); tok.Literal != "a" {
+		t.Fatalf("lexed %v, expected identifier a", tok)
+	}
+
+	// returning the inserted semicolon leaves b buffered
+	if tok := scanner.NextToken(); tok.Type != token.Semicolon {
+		t.Fatalf("lexed %v, expected an inserted semicolon", tok)
+	}
+
+	clone := scanner.Clone()
+
+	// advancing the original must not affect the clone
+	if tok := scanner.NextToken(); tok.Literal != "b" {
+		t.Errorf("lexed %v, expected identifier b", tok)
+	}
+
+	if tok := scanner.NextToken(); tok.Type != token.EOF {
+		t.Errorf("lexed %v, expected EOF", tok)
+	}
+
+	if tok := clone.NextToken(); tok.Literal != "b" {
+		t.Errorf("clone lexed %v, expected identifier b", tok)
+	}
+
+	if tok := clone.NextToken(); tok.Type != token.EOF {
+		t.Errorf("clone lexed %v, expected EOF", tok)
+	}
+}
+
+func TestUnread(t *testing.T) {
+	lexer := LexString("a b c", IgnoreErrors)
+
+	first := lexer.NextToken()
+	second := lexer.NextToken()
+
+	// pushed-back tokens return in LIFO order
+	lexer.Unread(first)
+	lexer.Unread(second)
+
+	if tok := lexer.NextToken(); !tok.Equal(second, false) {
+		t.Errorf("lexed %v, expected the pushed-back %v", tok, second)
+	}
+
+	if tok := lexer.Peek(); !tok.Equal(first, false) {
+		t.Errorf("peeked %v, expected the pushed-back %v", tok, first)
+	}
+
+	if tok := lexer.NextToken(); !tok.Equal(first, false) {
+		t.Errorf("lexed %v, expected the pushed-back %v", tok, first)
+	}
+
+	if tok := lexer.NextToken(); tok.Literal != "c" {
+		t.Errorf("lexed %v, expected identifier c", tok)
+	}
+}
+
+func TestDetectMixedScripts(t *testing.T) {
+	cases := []struct {
+		source string
+		errors int
+	}{
+		{"paypal", 0},
+		{"\u043f\u0435\u0440\u0435\u043c\u0435\u043d\u043d\u0430\u044f", 0}, // pure Cyrillic
+		{"p\u0430ypal", 1}, // Cyrillic \u0430 amid Latin letters
+	}
+
+	for _, c := range cases {
+		var list ErrorList
+		scanner := NewScanner(strings.NewReader(c.source), list.Add, DetectMixedScripts())
+
+		tok := scanner.NextToken()
+		if tok.Type != token.Identifier || tok.Literal != c.source {
+			t.Errorf("%q: lexed %v, expected the identifier as written", c.source, tok)
+		}
+
+		if len(list) != c.errors {
+			t.Errorf("%q: raised %v, expected %d errors", c.source, list, c.errors)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	// "é" written as 'e' followed by the U+0301 combining acute
+	decomposed := "café"
+	precomposed := "café"
+
+	scanner := NewScanner(strings.NewReader(decomposed), IgnoreErrors, Normalize())
+	if tok := scanner.NextToken(); tok.Literal != precomposed {
+		t.Errorf("lexed literal %q, expected the NFC form %q", tok.Literal, precomposed)
+	}
+
+	// without the option, the literal stays as written
+	scanner = NewScanner(strings.NewReader(decomposed), IgnoreErrors)
+	if tok := scanner.NextToken(); tok.Literal != decomposed {
+		t.Errorf("lexed literal %q, expected the raw form %q", tok.Literal, decomposed)
+	}
+}
+
+func TestEscapedIdentifiers(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected error
+	}{
+		{`\type\`, nil},
+		{`\_private\`, nil},
+		{`\\`, ErrEmptyEscapedIdent},
+		{`\1\`, ErrIllegalEscapedIdent},
+		{`\a+b\`, ErrIllegalEscapedIdent},
+		{`\open`, ErrUnclosedEscapedIdent},
+	}
+
+	for _, c := range cases {
+		var list ErrorList
+		scanner := NewScanner(strings.NewReader(c.source), list.Add)
+
+		tok := scanner.NextToken()
+		if tok.Type != token.Identifier || tok.Literal != c.source {
+			t.Errorf("%q: lexed %v, expected an identifier with the raw literal", c.source, tok)
+		}
+
+		switch {
+		case c.expected == nil && len(list) != 0:
+			t.Errorf("%q: unexpected errors: %v", c.source, list)
+		case c.expected != nil && (len(list) != 1 || !errors.Is(list[0], c.expected)):
+			t.Errorf("%q: raised %v, expected %v", c.source, list, c.expected)
+		}
+	}
+}
+
+func TestUnicodeEscapeValidation(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected error
+	}{
+		{`"\uD800"`, ErrSurrogateEscape},      // low surrogate half
+		{`"\uDFFF"`, ErrSurrogateEscape},      // high surrogate half
+		{`"\U00110000"`, ErrInvalidCodepoint}, // beyond U+10FFFF
+		{`"\U0001F600"`, nil},
+		{`"\uFFFD"`, nil},
+	}
+
+	for _, c := range cases {
+		var list ErrorList
+		scanner := NewScanner(strings.NewReader(c.source), list.Add)
+		scanner.NextToken()
+
+		switch {
+		case c.expected == nil && len(list) != 0:
+			t.Errorf("%q: unexpected errors: %v", c.source, list)
+		case c.expected != nil && (len(list) != 1 || !errors.Is(list[0], c.expected)):
+			t.Errorf("%q: raised %v, expected %v", c.source, list, c.expected)
+		}
+	}
+}
+
+func TestNumberTypes(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected token.Type
+	}{
+		{"42", token.Int},
+		{"0xFF", token.Int},
+		{"0o17", token.Int},
+		{"0b101", token.Int},
+		{"3.14", token.Float},
+		{"1e5", token.Float},
+		{"2E-3", token.Float},
+		{"0x1.8p3", token.Float},
+		{"0x1p-2", token.Float},
+		{"3i", token.Imaginary},
+		{"2.5i", token.Imaginary},
+		{"1e3i", token.Imaginary},
+	}
+
+	for _, c := range cases {
+		scanner := NewScanner(strings.NewReader(c.source), IgnoreErrors)
+
+		if tok := scanner.NextToken(); tok.Type != c.expected || tok.Literal != c.source {
+			t.Errorf("%q: lexed %v %q, expected %v", c.source, tok.Type, tok.Literal, c.expected)
+		}
+	}
+}
+
+func TestDigitSeparators(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected token.Type
+		errors   int
+	}{
+		{"1_000_000", token.Int, 0},
+		{"0xFF_FF", token.Int, 0},
+		{"1_000.000_1", token.Float, 0},
+		{"5_", token.Int, 1},
+		{"5__0", token.Int, 1},
+		{"1_.5", token.Float, 1},
+
+		// every base enforces the same between-two-digits rule,
+		// including for separators directly after the base prefix
+		{"0b1_01", token.Int, 0},
+		{"0b_1", token.Int, 1},
+		{"0b1_", token.Int, 1},
+		{"0o7_7", token.Int, 0},
+		{"0o_7", token.Int, 1},
+		{"0o7_", token.Int, 1},
+		{"0xF_F", token.Int, 0},
+		{"0x_1", token.Int, 1},
+		{"0xF_", token.Int, 1},
+	}
+
+	for _, c := range cases {
+		errors := 0
+		scanner := NewScanner(strings.NewReader(c.source), func(error) {
+			errors++
+		})
+
+		if tok := scanner.NextToken(); tok.Type != c.expected || tok.Literal != c.source {
+			t.Errorf("%q: lexed %v %q", c.source, tok.Type, tok.Literal)
+		}
+
+		if errors != c.errors {
+			t.Errorf("%q: lexed with %d errors, expected %d", c.source, errors, c.errors)
+		}
+	}
+
+	// an illegal separator is reported at its own position
+	errs := Validate(strings.NewReader("12_"))
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "1:3") {
+		t.Errorf("raised %v, expected a single error at 1:3", errs)
+	}
+}
+
+func TestTokenEnd(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("ab + \"xy\"\ncd"), IgnoreErrors)
+
+	expected := []struct {
+		start, end file.Pos
+	}{
+		{file.Pos{Line: 1, Column: 1}, file.Pos{Line: 1, Column: 3}},   // ab
+		{file.Pos{Line: 1, Column: 4}, file.Pos{Line: 1, Column: 5}},   // +
+		{file.Pos{Line: 1, Column: 6}, file.Pos{Line: 1, Column: 10}},  // "xy"
+		{file.Pos{Line: 1, Column: 10}, file.Pos{Line: 1, Column: 10}}, // inserted semicolon
+		{file.Pos{Line: 2, Column: 1}, file.Pos{Line: 2, Column: 3}},   // cd
+	}
+
+	for i, positions := range expected {
+		tok := scanner.NextToken()
+		if tok.Pos != positions.start || tok.End != positions.end {
+			t.Errorf(
+				"token %d (%q): got %v-%v, expected %v-%v",
+				i, tok.Literal, tok.Pos, tok.End, positions.start, positions.end,
+			)
+		}
+	}
+}
+
+func TestLexFile(t *testing.T) {
+	source := file.NewFile("main.kr", strings.NewReader("\"unterminated\n"))
+
+	var errs []error
+	lexer := LexFile(source, func(err error) {
+		errs = append(errs, err)
+	})
+
+	drain(lexer.NextToken)
+
+	if len(errs) != 1 {
+		t.Fatalf("lexed with %d errors, expected 1: %v", len(errs), errs)
+	}
+
+	if msg := errs[0].Error(); !strings.HasPrefix(msg, "main.kr:1:") {
+		t.Errorf("error %q does not carry the file name", msg)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	lexer := Lex(strings.NewReader("a + b"), IgnoreErrors)
+
+	var types []token.Type
+	lexer.Tokens()(func(tok token.Token) bool {
+		types = append(types, tok.Type)
+		return true
+	})
+
+	expected := []token.Type{token.Identifier, token.Plus, token.Identifier}
+	if len(types) != len(expected) {
+		t.Fatalf("yielded %d tokens, expected %d", len(types), len(expected))
+	}
+
+	for i, tokenType := range types {
+		if tokenType != expected[i] {
+			t.Errorf("token %d: got %v, expected %v", i, tokenType, expected[i])
+		}
+	}
+}
+
+func TestTokensBreak(t *testing.T) {
+	lexer := Lex(strings.NewReader("a + b + c"), IgnoreErrors)
+
+	yielded := 0
+	lexer.Tokens()(func(tok token.Token) bool {
+		yielded++
+		return false
+	})
+
+	if yielded != 1 {
+		t.Errorf("yielded %d tokens after break, expected 1", yielded)
+	}
+
+	if tok := lexer.NextToken(); tok.Type != token.EOF {
+		t.Errorf("NextToken after break returned %v, expected EOF", tok.Type)
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	var errors ErrorList
+	scanner := NewScanner(strings.NewReader("a $\nb $"), errors.Add)
+	drain(scanner.NextToken)
+
+	if len(errors) != 2 {
+		t.Fatalf("collected %d errors, expected 2", len(errors))
+	}
+
+	reversed := ErrorList{errors[1], errors[0]}
+	sorted := reversed.Sorted()
+
+	if sorted[0] != errors[0] || sorted[1] != errors[1] {
+		t.Error("Sorted did not order the errors by position")
+	}
+
+	if !strings.Contains(errors.Error(), "and 1 more error") {
+		t.Errorf("summary %q does not mention the error count", errors.Error())
+	}
+}
+
+func TestIllegalRun(t *testing.T) {
+	var raised []error
+	scanner := NewScanner(strings.NewReader("a $$$ b"), func(err error) {
+		raised = append(raised, err)
+	})
+
+	tokens := drain(scanner.NextToken)
+
+	expected := []token.Type{
+		token.Identifier, token.Illegal, token.Identifier, token.EOF,
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("lexed %d tokens, expected %d", len(tokens), len(expected))
+	}
+
+	if tokens[1].Literal != "$$$" {
+		t.Errorf("Illegal literal is %q, expected %q", tokens[1].Literal, "$$$")
+	}
+
+	if len(raised) != 1 {
+		t.Errorf("raised %d errors, expected 1 for the whole run", len(raised))
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	var raised []error
+	scanner := NewScanner(strings.NewReader("$ $ $ $ $ $"), func(err error) {
+		raised = append(raised, err)
+	}, MaxErrors(3))
+
+	tokens := drain(scanner.NextToken)
+
+	// three errors plus the final ErrTooManyErrors
+	if len(raised) != 4 {
+		t.Fatalf("raised %d errors, expected 4", len(raised))
+	}
+
+	if !errors.Is(raised[3], ErrTooManyErrors) {
+		t.Errorf("final error is %v, expected ErrTooManyErrors", raised[3])
+	}
+
+	// three Illegal tokens plus EOF: the rest of the source is
+	// abandoned
+	if len(tokens) != 4 {
+		t.Errorf("lexed %d tokens, expected 4", len(tokens))
+	}
+}
+
+// TestShebang checks that a #! line is dropped at the very start of
+// the source and lexed as an ordinary comment anywhere else.
+func TestShebang(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("#!/usr/bin/env krypton\nlet x"), IgnoreErrors)
+	tokens := drain(scanner.NextToken)
+
+	if len(tokens) != 3 || tokens[0].Type != token.Let {
+		t.Errorf("shebang at line 1 was not dropped: lexed %v", tokens)
+	}
+
+	scanner = NewScanner(strings.NewReader("let x\n#! not a shebang"), IgnoreErrors)
+	tokens = drain(scanner.NextToken)
+
+	if len(tokens) != 5 || tokens[3].Type != token.Comment {
+		t.Errorf("#! at line 2 did not lex as a comment: lexed %v", tokens)
+	}
+}
+
+func TestDocComment(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("## documentation\n# plain"), IgnoreErrors)
+
+	if tok := scanner.NextToken(); tok.Type != token.DocComment || tok.Literal != "## documentation" {
+		t.Errorf("lexed %v, expected a DOC_COMMENT", tok)
+	}
+
+	if tok := scanner.NextToken(); tok.Type != token.Comment || tok.Literal != "# plain" {
+		t.Errorf("lexed %v, expected a COMMENT", tok)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("a # note\nb"), IgnoreErrors,
+		Transform(func(tok token.Token) token.Token {
+			switch tok.Type {
+			case token.Comment:
+				return token.Token{Type: Drop}
+			case token.Identifier:
+				tok.Literal = strings.ToUpper(tok.Literal)
+			}
+
+			return tok
+		}))
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: token.Identifier, Literal: "A"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Identifier, Literal: "B"},
+		{Type: token.EOF},
+	})
+}
+
+func TestIndentation(t *testing.T) {
+	source := "f\n  a\n  b\n\n    c\n  # note\nd\n"
+	scanner := NewScanner(strings.NewReader(source), IgnoreErrors, Indentation())
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: token.Identifier, Literal: "f"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Indent},
+		{Type: token.Identifier, Literal: "a"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Identifier, Literal: "b"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Indent},
+		{Type: token.Identifier, Literal: "c"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Comment, Literal: "# note"},
+		{Type: token.Dedent},
+		{Type: token.Dedent},
+		{Type: token.Identifier, Literal: "d"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.EOF},
+	})
+}
+
+func TestIndentationEOF(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("a\n  b"), IgnoreErrors, Indentation())
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: token.Identifier, Literal: "a"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Indent},
+		{Type: token.Identifier, Literal: "b"},
+		{Type: token.Dedent},
+		{Type: token.EOF},
+	})
+}
+
+func TestIndentationMismatch(t *testing.T) {
+	var list ErrorList
+	scanner := NewScanner(strings.NewReader("a\n    b\n  c\n"), list.Add, Indentation())
+	drain(scanner.NextToken)
+
+	if len(list) != 1 || !errors.Is(list[0], ErrIndentMismatch) {
+		t.Errorf("raised %v, expected a single ErrIndentMismatch", list)
+	}
+}
+
+func TestEOFPosition(t *testing.T) {
+	end := file.Pos{Line: 2, Column: 1}
+
+	lexers := map[string]func() token.Token{
+		"lexer":   LexString("ab + cd\n", IgnoreErrors).NextToken,
+		"scanner": NewScanner(strings.NewReader("ab + cd\n"), IgnoreErrors).NextToken,
+	}
+
+	for name, next := range lexers {
+		tokens := drain(next)
+
+		last := tokens[len(tokens)-1]
+		if last.Type != token.EOF || last.Pos != end || last.End != end {
+			t.Errorf("%s: got %v, expected EOF @ %v", name, last, end)
+		}
+	}
+}
+
+func TestNextTokenPastEOF(t *testing.T) {
+	lexer := LexString("ab", IgnoreErrors)
+
+	ident := lexer.NextToken()
+	if ident.Type != token.Identifier {
+		t.Fatalf("lexed %v, expected an identifier", ident)
+	}
+
+	for i := 0; i < 100; i++ {
+		tok := lexer.NextToken()
+		if tok.Type != token.EOF || tok.Pos != ident.End || tok.End != ident.End {
+			t.Fatalf("call %d past EOF: got %v, expected EOF @ %v", i, tok, ident.End)
+		}
+	}
+}
+
+func TestKeepWhitespace(t *testing.T) {
+	source := "let x = 1\n\nif x {\n\treturn x # done\n}\n"
+
+	tokens, errs := TokenizeAll(strings.NewReader(source), KeepWhitespace())
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	var builder strings.Builder
+	for _, tok := range tokens {
+		builder.WriteString(tok.Literal)
+	}
+
+	if reconstructed := builder.String(); reconstructed != source {
+		t.Errorf("reconstructed %q, expected %q", reconstructed, source)
+	}
+
+	semicolons := 0
+	for _, tok := range tokens {
+		if tok.Type == token.Semicolon {
+			semicolons++
+
+			if tok.Literal != "\n" {
+				t.Errorf("inserted semicolon has literal %q, expected the newline", tok.Literal)
+			}
+		}
+	}
+
+	// after 1, x, and } respectively
+	if semicolons != 3 {
+		t.Errorf("inserted %d semicolons, expected 3", semicolons)
+	}
+}
+
+func TestRouteComments(t *testing.T) {
+	var comments []token.Token
+	scanner := NewScanner(strings.NewReader("a # trailing\n## doc\nb"), IgnoreErrors,
+		RouteComments(func(tok token.Token) {
+			comments = append(comments, tok)
+		}))
+
+	tokens := drain(scanner.NextToken)
+
+	expected := []token.Type{
+		token.Identifier, token.Semicolon, token.Identifier, token.EOF,
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("lexed %d tokens, expected %d: %v", len(tokens), len(expected), tokens)
+	}
+
+	for i, tok := range tokens {
+		if tok.Type != expected[i] {
+			t.Errorf("token %d: got %v, expected %v", i, tok.Type, expected[i])
+		}
+	}
+
+	if len(comments) != 2 || comments[0].Literal != "# trailing" || comments[1].Literal != "## doc" {
+		t.Errorf("routed comments %v, expected the two comment tokens", comments)
+	}
+}
+
+// TestCRLF checks that \r\n line endings behave exactly like lone
+// \n ones: one inserted semicolon per line break and no stray column
+// from the \r.
+func TestCRLF(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("a\r\nb\nc\r\n"), IgnoreErrors)
+	tokens := drain(scanner.NextToken)
+
+	expected := []struct {
+		tokenType token.Type
+		pos       file.Pos
+	}{
+		{token.Identifier, file.Pos{Line: 1, Column: 1}},
+		{token.Semicolon, file.Pos{Line: 1, Column: 2}},
+		{token.Identifier, file.Pos{Line: 2, Column: 1}},
+		{token.Semicolon, file.Pos{Line: 2, Column: 2}},
+		{token.Identifier, file.Pos{Line: 3, Column: 1}},
+		{token.Semicolon, file.Pos{Line: 3, Column: 2}},
+		{token.EOF, file.Pos{Line: 4, Column: 1}},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("lexed %d tokens, expected %d", len(tokens), len(expected))
+	}
+
+	for i, tok := range tokens {
+		if tok.Type != expected[i].tokenType || tok.Pos != expected[i].pos {
+			t.Errorf("token %d: got %v at %v, expected %v at %v",
+				i, tok.Type, tok.Pos, expected[i].tokenType, expected[i].pos)
+		}
+	}
+}
+
+// TestDotFloats pins down how dots and numbers interact: a decimal
+// point is part of a number only when a digit directly follows it,
+// so trailing-dot and empty-fraction forms fall to the operator
+// lexer instead of raising errors.
+func TestDotFloats(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected []token.Type
+	}{
+		{"1.5", []token.Type{token.Float, token.EOF}},
+		{"1.", []token.Type{token.Int, token.Period, token.EOF}},
+		{"1.e5", []token.Type{
+			token.Int, token.Period, token.Identifier, token.EOF,
+		}},
+		{".5", []token.Type{token.Float, token.EOF}},
+		{"x = .5", []token.Type{
+			token.Identifier, token.Equal, token.Float, token.EOF,
+		}},
+		{"c ? .5 : 1", []token.Type{
+			token.Identifier, token.Question, token.Float,
+			token.Colon, token.Int, token.EOF,
+		}},
+		// the imaginary suffix binds only without whitespace
+		{"3 i", []token.Type{token.Int, token.Identifier, token.EOF}},
+	}
+
+	for _, c := range cases {
+		scanner := NewScanner(strings.NewReader(c.source), IgnoreErrors)
+		tokens := drain(scanner.NextToken)
+
+		if len(tokens) != len(c.expected) {
+			t.Errorf("%q: lexed %d tokens, expected %d", c.source, len(tokens), len(c.expected))
+			continue
+		}
+
+		for i, tok := range tokens {
+			if tok.Type != c.expected[i] {
+				t.Errorf("%q: token %d: got %v, expected %v", c.source, i, tok.Type, c.expected[i])
+			}
+		}
+	}
+}
+
+func TestStrictOctal(t *testing.T) {
+	cases := []struct {
+		source string
+		strict bool
+		errors int
+	}{
+		{"0123", false, 0},
+		{"0123", true, 1},
+		{"0o123", true, 0},
+		{"0", true, 0},
+		{"0.5", true, 0},
+	}
+
+	for _, c := range cases {
+		options := []Option{}
+		if c.strict {
+			options = append(options, StrictOctal())
+		}
+
+		errors := 0
+		scanner := NewScanner(strings.NewReader(c.source), func(error) {
+			errors++
+		}, options...)
+		drain(scanner.NextToken)
+
+		if errors != c.errors {
+			t.Errorf("%q (strict %v): lexed with %d errors, expected %d", c.source, c.strict, errors, c.errors)
+		}
+	}
+}
+
+func TestStrictHexFloat(t *testing.T) {
+	cases := []struct {
+		source string
+		strict bool
+		errors int
+	}{
+		{"0x1.8p3", true, 0},
+		{"0x1p3", true, 0},
+		{"0x1.8", true, 1},
+		{"0x1.8", false, 0},
+		{"0x1", true, 0},
+		{"1.5", true, 0},
+	}
+
+	for _, c := range cases {
+		options := []Option{}
+		if c.strict {
+			options = append(options, StrictHexFloat())
+		}
+
+		raised := 0
+		scanner := NewScanner(strings.NewReader(c.source), func(err error) {
+			raised++
+
+			if !errors.Is(err, ErrHexFloatExponent) {
+				t.Errorf("%q: raised %v, expected it to wrap ErrHexFloatExponent", c.source, err)
+			}
+		}, options...)
+		drain(scanner.NextToken)
+
+		if raised != c.errors {
+			t.Errorf("%q (strict %v): lexed with %d errors, expected %d", c.source, c.strict, raised, c.errors)
+		}
+	}
+}
+
+func TestRejectNumberSuffixes(t *testing.T) {
+	cases := []struct {
+		source string
+		errors int
+	}{
+		{"123abc", 1},
+		{"123 abc", 0},
+		{"0x1g", 1},
+		{"123+abc", 0},
+		{"1.5e3x", 1},
+		{"42i", 0},
+	}
+
+	for _, c := range cases {
+		raised := 0
+		scanner := NewScanner(strings.NewReader(c.source), func(err error) {
+			raised++
+
+			if !errors.Is(err, ErrNumberSuffix) {
+				t.Errorf("%q: raised %v, expected it to wrap ErrNumberSuffix", c.source, err)
+			}
+		}, RejectNumberSuffixes())
+		drain(scanner.NextToken)
+
+		if raised != c.errors {
+			t.Errorf("%q: lexed with %d errors, expected %d", c.source, raised, c.errors)
+		}
+	}
+
+	// without the option, adjacency lexes silently
+	if errs := Validate(strings.NewReader("123abc")); len(errs) != 0 {
+		t.Errorf("123abc without the option raised %v, expected no errors", errs)
+	}
+
+	// the suffix itself is named in the error message
+	errs := Validate(strings.NewReader("123abc"), RejectNumberSuffixes())
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `"abc"`) {
+		t.Errorf("raised %v, expected a single error naming the suffix", errs)
+	}
+}
+
+func TestAssignmentHint(t *testing.T) {
+	// the Transform hook doubles as a post-emit observer: a tool
+	// tracking a little context can flag an = lexed inside an if
+	// condition, where == was likely meant
+	var hints []file.Pos
+	inCondition := false
+
+	hook := Transform(func(tok token.Token) token.Token {
+		switch {
+		case tok.Type == token.If:
+			inCondition = true
+		case tok.Type == token.LeftBrace:
+			inCondition = false
+		case inCondition && tok.Type.IsAssignment():
+			hints = append(hints, tok.Pos)
+		}
+
+		return tok
+	})
+
+	scanner := NewScanner(strings.NewReader("if x = 5 { x == 5 }"), nil, hook)
+	drain(scanner.NextToken)
+
+	if len(hints) != 1 || (hints[0] != file.Pos{Line: 1, Column: 6}) {
+		t.Errorf("flagged %v, expected a single hint at 1:6", hints)
+	}
+}
+
+func TestSkipInteriorBOM(t *testing.T) {
+	source := "a\nb\n\uFEFFc\n"
+
+	for _, skip := range []bool{false, true} {
+		options := []Option{}
+		if skip {
+			options = append(options, SkipInteriorBOM())
+		}
+
+		var raised []error
+		scanner := NewScanner(strings.NewReader(source), func(err error) {
+			raised = append(raised, err)
+		}, options...)
+		tokens := drain(scanner.NextToken)
+
+		if skip && len(raised) != 0 {
+			t.Errorf("skipping mode raised %v, expected no errors", raised)
+		}
+
+		if !skip && (len(raised) != 1 || !errors.Is(raised[0], ErrIllegalBOM)) {
+			t.Errorf("strict mode raised %v, expected a single ErrIllegalBOM", raised)
+		}
+
+		// in either mode, the BOM is skipped without reaching a
+		// token literal
+		for _, tok := range tokens {
+			if strings.ContainsRune(tok.Literal, '\uFEFF') {
+				t.Errorf("skip %v: literal %q contains the BOM", skip, tok.Literal)
+			}
+		}
+
+		if tok := tokens[4]; tok.Type != token.Identifier || tok.Literal != "c" {
+			t.Errorf("skip %v: token after the BOM is %v, expected IDENT(\"c\")", skip, tok)
+		}
+	}
+}
+
+func TestCommentIntroducer(t *testing.T) {
+	source := "a // hi\nb / c\n/// doc\n"
+	scanner := NewScanner(strings.NewReader(source), func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}, CommentIntroducer("//"))
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: token.Identifier, Literal: "a"},
+		{Type: token.Comment, Literal: "// hi"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Identifier, Literal: "b"},
+		{Type: token.Slash, Literal: "/"},
+		{Type: token.Identifier, Literal: "c"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.DocComment, Literal: "/// doc"},
+		{Type: token.EOF},
+	})
+
+	// the explicit default behaves exactly like no option at all
+	scanner = NewScanner(strings.NewReader("# hi\n## doc\n"), func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}, CommentIntroducer("#"))
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: token.Comment, Literal: "# hi"},
+		{Type: token.DocComment, Literal: "## doc"},
+		{Type: token.EOF},
+	})
+
+	// with another introducer configured, # is no longer special
+	errs := Validate(strings.NewReader("# hi\n"), CommentIntroducer("//"))
+	if len(errs) == 0 {
+		t.Error("# under a // introducer lexed without errors")
+	}
+}
+
+func TestSlashSlashComment(t *testing.T) {
+	// C-style // comments are built in alongside the default #
+	// introducer, while a lone slash stays the division operator
+	source := "a / b\nc // note\n/// doc\n# still\n"
+	scanner := NewScanner(strings.NewReader(source), func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	})
+
+	expectTokens(t, scanner.NextToken, []token.Token{
+		{Type: token.Identifier, Literal: "a"},
+		{Type: token.Slash, Literal: "/"},
+		{Type: token.Identifier, Literal: "b"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.Identifier, Literal: "c"},
+		{Type: token.Comment, Literal: "// note"},
+		{Type: token.Semicolon, Literal: "\n"},
+		{Type: token.DocComment, Literal: "/// doc"},
+		{Type: token.Comment, Literal: "# still"},
+		{Type: token.EOF},
+	})
+}
+
+func TestLoneOperatorAtEOF(t *testing.T) {
+	// a lone operator at the very end of the input must lex as its
+	// own type, never degrade to a zero-valued EOF token
+	cases := []struct {
+		source   string
+		expected token.Type
+	}{
+		{"+", token.Plus},
+		{"&", token.Amp},
+		{"=", token.Equal},
+		{"?", token.Question},
+		{"a -", token.Minus},
+	}
+
+	for _, c := range cases {
+		scanner := NewScanner(strings.NewReader(c.source), func(err error) {
+			t.Errorf("%q: unexpected error: %v", c.source, err)
+		})
+
+		tokens := drain(scanner.NextToken)
+		last := tokens[len(tokens)-2]
+		if last.Type != c.expected || last.Literal != c.expected.String() {
+			t.Errorf("%q: last token is %v, expected %v", c.source, last, c.expected)
+		}
+	}
+}
+
+func TestTabWidth(t *testing.T) {
+	cases := []struct {
+		source   string
+		width    int
+		expected file.Pos
+	}{
+		{"\ta", 1, file.Pos{Line: 1, Column: 2}},
+		{"\ta", 4, file.Pos{Line: 1, Column: 5}},
+		{"  \ta", 4, file.Pos{Line: 1, Column: 5}},
+		{"\t\ta", 8, file.Pos{Line: 1, Column: 17}},
+	}
+
+	for _, c := range cases {
+		scanner := NewScanner(strings.NewReader(c.source), IgnoreErrors, TabWidth(c.width))
+
+		if tok := scanner.NextToken(); tok.Pos != c.expected {
+			t.Errorf("%q (width %d): token starts at %v, expected %v", c.source, c.width, tok.Pos, c.expected)
+		}
+	}
+}
+
+func TestTabWidthInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("TabWidth(0) did not panic")
+		}
+	}()
+
+	TabWidth(0)
+}
+
+func TestLexStringBytes(t *testing.T) {
+	fromString := drain(LexString("a + b", IgnoreErrors).NextToken)
+	fromBytes := drain(LexBytes([]byte("a + b"), IgnoreErrors).NextToken)
+
+	if len(fromString) != len(fromBytes) {
+		t.Fatalf("lexed %d and %d tokens from identical sources", len(fromString), len(fromBytes))
+	}
+
+	for i := range fromString {
+		if fromString[i].Type != fromBytes[i].Type || fromString[i].Literal != fromBytes[i].Literal {
+			t.Errorf("token %d: %v from string, %v from bytes", i, fromString[i], fromBytes[i])
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	lexer := Lex(strings.NewReader("a $"), IgnoreErrors)
+	drain(lexer.NextToken)
+
+	if lexer.Errors != 1 {
+		t.Fatalf("lexed with %d errors, expected 1", lexer.Errors)
+	}
+
+	lexer.Reset(strings.NewReader("b"))
+
+	if lexer.Errors != 0 {
+		t.Errorf("Errors is %d after Reset, expected 0", lexer.Errors)
+	}
+
+	tokens := drain(lexer.NextToken)
+	if len(tokens) != 2 || tokens[0].Type != token.Identifier {
+		t.Fatalf("lexed %v after Reset, expected an identifier and EOF", tokens)
+	}
+
+	if tokens[0].Pos != file.Origin {
+		t.Errorf("first token starts at %v after Reset, expected %v", tokens[0].Pos, file.Origin)
+	}
+}
+
+func TestClose(t *testing.T) {
+	lexer := Lex(strings.NewReader("a b c d e f g"), IgnoreErrors)
+
+	lexer.NextToken()
+	lexer.NextToken()
+
+	lexer.Close()
+	lexer.Close() // closing twice must be safe
+
+	for i := 0; i < 4; i++ {
+		if tok := lexer.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("NextToken after Close returned %v %q", tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestCloseAfterEOF(t *testing.T) {
+	lexer := Lex(strings.NewReader("a"), IgnoreErrors)
+	drain(lexer.NextToken)
+
+	lexer.Close()
+
+	if tok := lexer.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("NextToken after Close returned %v %q", tok.Type, tok.Literal)
+	}
+}
+
+func TestLexContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lexer := LexContext(ctx, strings.NewReader("a b c d e f g"), IgnoreErrors)
+
+	lexer.NextToken()
+	cancel()
+
+	// tokens already in flight may still be delivered, but the
+	// stream must reach EOF shortly after cancellation
+	for i := 0; i < 20; i++ {
+		if lexer.NextToken().Type == token.EOF {
+			return
+		}
+	}
+
+	t.Fatal("token stream did not reach EOF after cancellation")
+}
+
+func TestScannerMatchesLexer(t *testing.T) {
+	sources := []string{
+		"",
+		"let x = 1 + 2 * 3\n",
+		"func add(a, b) { return a + b }\n",
+		"# just a comment\n'x' \"string\" 0xFF 3.14\n",
+		"a <<= b >> 2; c != d\n",
+	}
+
+	for _, source := range sources {
+		lexer := Lex(strings.NewReader(source), IgnoreErrors)
+		scanner := NewScanner(strings.NewReader(source), IgnoreErrors)
+
+		streamed := drain(lexer.NextToken)
+		scanned := drain(scanner.NextToken)
+
+		if len(streamed) != len(scanned) {
+			t.Fatalf(
+				"%q: lexer produced %d tokens, scanner produced %d",
+				source, len(streamed), len(scanned),
+			)
+		}
+
+		for i := range streamed {
+			if streamed[i].Type != scanned[i].Type || streamed[i].Literal != scanned[i].Literal {
+				t.Errorf(
+					"%q: token %d: lexer produced %v, scanner produced %v",
+					source, i, streamed[i], scanned[i],
+				)
+			}
+		}
+	}
+}
+
+// TestLexBytesMatchesLex lexes the same tricky source through the
+// streaming and buffer-backed constructors under a few option sets,
+// asserting that the two paths produce identical token streams.
+func TestLexBytesMatchesLex(t *testing.T) {
+	source := "let caf\u00e9 = \"a \\\"quoted\\\" value\"\r\n" +
+		"x = 0x1.8p3 + '\\n' # trailing comment\n" +
+		"a\uFEFFb\n" +
+		"bad \xFF byte\n"
+
+	configs := map[string][]Option{
+		"Default":        {MaxErrors(0)},
+		"KeepWhitespace": {MaxErrors(0), KeepWhitespace()},
+		"SkipBOM":        {MaxErrors(0), SkipInteriorBOM()},
+	}
+
+	for name, options := range configs {
+		t.Run(name, func(t *testing.T) {
+			streamed, _ := TokenizeAll(strings.NewReader(source), options...)
+			streamed = streamed[:len(streamed)-1] // trim the EOF token
+
+			lexer := LexBytes([]byte(source), IgnoreErrors, options...)
+			buffered := []token.Token{}
+			for tok := lexer.NextToken(); tok.Type != token.EOF; tok = lexer.NextToken() {
+				buffered = append(buffered, tok)
+			}
+
+			if len(buffered) != len(streamed) {
+				t.Fatalf("token counts differ: streaming %d, buffer %d",
+					len(streamed), len(buffered))
+			}
+
+			for i, tok := range buffered {
+				if !tok.Equal(streamed[i], false) {
+					t.Errorf("token %d differs: streaming %s, buffer %s",
+						i, streamed[i], tok)
+				}
+			}
+		})
+	}
+}
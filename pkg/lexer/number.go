@@ -0,0 +1,153 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"unicode"
+
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// lexNumber lexes a numeric literal token in any of the supported
+// bases, emitting an Int token, or a Float token if a fractional
+// part or an exponent was consumed. Either kind becomes an Imaginary
+// token when suffixed with an 'i'.
+func (lexer *Lexer) lexNumber() {
+	base := 10
+	required := false
+	isFloat := false
+
+	if lexer.current == '0' {
+		lexer.consume()
+
+		switch unicode.ToLower(lexer.current) {
+		case 'x':
+			base, required = 16, true
+			lexer.consume()
+		case 'o':
+			base, required = 8, true
+			lexer.consume()
+		case 'b':
+			base, required = 2, true
+			lexer.consume()
+		default:
+			// legacy octal literal, like 0123
+			if lexer.strictOctal && token.IsDigit(lexer.current, 10) {
+				lexer.raise(ErrLegacyOctal)
+			}
+
+			base = 8
+		}
+	}
+
+	lexer.consumeDigits(base, required)
+
+	// A '.' only continues the number when a digit directly follows
+	// it: otherwise the dot is left to the operator lexer. This is
+	// the rule which keeps 1..5 a range; it also means 1. lexes as
+	// an integer followed by a period, and 1.e5 as 1 . e5 rather
+	// than a float with an empty fraction — a fraction requires at
+	// least one digit before its exponent.
+	if lexer.current == '.' && token.IsDigit(lexer.peek(), base) {
+		isFloat = true
+		lexer.consume()
+		lexer.consumeDigits(base, true)
+	}
+
+	exponent := false
+
+	switch lexer.current {
+	case 'p', 'P':
+		// 'p' exponents are only valid on hexadecimal literals
+		if base != 16 {
+			lexer.raise(ErrDecimalPowerExp)
+		}
+
+		isFloat, exponent = true, true
+		lexer.consumeExponent()
+	case 'e', 'E':
+		// 'e' is a digit in base 16, so this case is unreachable
+		// for hexadecimal literals
+		isFloat, exponent = true, true
+		lexer.consumeExponent()
+	}
+
+	// under StrictHexFloat, a hexadecimal fraction like 0x1.8 is only
+	// valid when followed by an explicit 'p' exponent
+	if lexer.strictHexFl && base == 16 && isFloat && !exponent {
+		lexer.raise(ErrHexFloatExponent)
+	}
+
+	// an 'i' directly after the number, with no intervening
+	// whitespace, marks an imaginary literal
+	switch {
+	case lexer.current == 'i':
+		lexer.consume()
+		lexer.emit(token.Imaginary)
+	case isFloat:
+		lexer.emit(token.Float)
+	default:
+		lexer.emit(token.Int)
+	}
+
+	// under RejectNumberSuffixes, an identifier starting directly at
+	// the end of the number is flagged here, while the two tokens are
+	// still adjacent, and reported by lexIdentifier once it has
+	// consumed the suffix
+	lexer.afterNumber = lexer.numSuffix && isIdentStart(lexer.current)
+}
+
+// consumeDigits consumes a run of digits in the given base, raising
+// an error if required is true and no digits are present. Digits may
+// be grouped with '_' separators, each of which must appear between
+// two digits of the base: leading, trailing, and doubled separators,
+// including a separator directly after a base prefix, are errors.
+// The rule is enforced here for every base alike, and each illegal
+// separator is reported at its own position, before it is consumed.
+func (lexer *Lexer) consumeDigits(base int, required bool) {
+	digits := 0
+
+	for {
+		switch {
+		case lexer.current == '_':
+			if digits == 0 || !token.IsDigit(lexer.peek(), base) {
+				lexer.raise(ErrDigitSeparator)
+			}
+
+			lexer.consume()
+
+		case token.IsDigit(lexer.current, base):
+			digits++
+			lexer.consume()
+
+		default:
+			if required && digits == 0 {
+				lexer.raise(ErrExpectedDigits)
+			}
+
+			return
+		}
+	}
+}
+
+// consumeExponent consumes an exponent marker, its optional sign,
+// and its digits.
+func (lexer *Lexer) consumeExponent() {
+	lexer.consume() // the exponent marker
+
+	if lexer.current == '+' || lexer.current == '-' {
+		lexer.consume()
+	}
+
+	lexer.consumeDigits(10, true)
+}
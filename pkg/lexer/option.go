@@ -0,0 +1,245 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"unicode/utf8"
+
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// Option configures a Lexer or Scanner at construction time.
+type Option func(*Lexer)
+
+// defaultMaxErrors is the error limit applied to lexers constructed
+// without a MaxErrors option.
+const defaultMaxErrors = 10
+
+// MaxErrors limits the number of errors the lexer raises before
+// abandoning the source: once the limit is reached, a final
+// ErrTooManyErrors is reported and the token stream is closed. The
+// default limit is 10; a limit of 0 or less disables it entirely.
+func MaxErrors(limit int) Option {
+	return func(lexer *Lexer) {
+		lexer.maxErrors = limit
+	}
+}
+
+// TabWidth makes the lexer advance the column to the next tab stop
+// of the given width when it consumes a tab, so positions line up
+// with editors rendering tabs 4 or 8 columns wide. The default width
+// is 1, under which a tab advances the column like any other rune.
+// TabWidth panics on widths smaller than 1.
+func TabWidth(width int) Option {
+	if width < 1 {
+		panic("lexer: TabWidth: width must be at least 1")
+	}
+
+	return func(lexer *Lexer) {
+		lexer.tabWidth = width
+	}
+}
+
+// StrictUTF8 makes illegal UTF-8 encodings fatal: the first
+// ErrIllegalUTF8 raised abandons the rest of the source and closes
+// the token stream, instead of skipping the offending byte and
+// continuing. Pipelines which must reject non-UTF-8 input outright
+// use this to avoid lexing a garbage token stream out of binary
+// input. By default, the error is raised per offending byte and
+// lexing continues.
+func StrictUTF8() Option {
+	return func(lexer *Lexer) {
+		lexer.strictUTF8 = true
+	}
+}
+
+// StrictOctal makes the lexer raise ErrLegacyOctal for octal
+// literals written with a bare leading zero, like 0123, requiring
+// the explicit 0o prefix instead. By default, such literals are
+// silently lexed as octal.
+func StrictOctal() Option {
+	return func(lexer *Lexer) {
+		lexer.strictOctal = true
+	}
+}
+
+// StrictHexFloat makes the lexer raise ErrHexFloatExponent for
+// hexadecimal floats written without a 'p' exponent, like 0x1.8,
+// matching the Go and C rule that a hexadecimal fraction is only
+// meaningful with an explicit binary exponent. By default, such
+// literals are silently lexed as floats.
+func StrictHexFloat() Option {
+	return func(lexer *Lexer) {
+		lexer.strictHexFl = true
+	}
+}
+
+// RejectNumberSuffixes makes the lexer raise an error wrapping
+// ErrNumberSuffix for an identifier directly adjacent to the end of a
+// number literal, so a typo like 123abc no longer lexes silently as a
+// number followed by an identifier. Both tokens are still emitted
+// exactly as without the option; whitespace or an operator between
+// the number and the identifier is fine. This mirrors Go's lexer,
+// which rejects such suffixes outright.
+func RejectNumberSuffixes() Option {
+	return func(lexer *Lexer) {
+		lexer.numSuffix = true
+	}
+}
+
+// SkipInteriorBOM makes the lexer silently skip byte order marks in
+// the middle of the source instead of raising ErrIllegalBOM, for
+// legacy files assembled by concatenating UTF-8 sources which each
+// begin with a BOM. A skipped BOM never appears in a token literal.
+// By default, a BOM is only permitted as the very first rune.
+func SkipInteriorBOM() Option {
+	return func(lexer *Lexer) {
+		lexer.skipBOM = true
+	}
+}
+
+// CommentIntroducer sets the rune sequence which opens a line
+// comment, like "//" for C-style dialects, replacing the default "#".
+// The introducer must be one or two runes long, and a comment which
+// repeats its final rune, like ## or ///, still lexes as a
+// DocComment. An introducer sharing its first rune with an operator
+// only takes precedence when matched in full, so with "//" a lone
+// slash still lexes as the division operator. CommentIntroducer
+// panics on an empty or overlong introducer.
+func CommentIntroducer(introducer string) Option {
+	if count := utf8.RuneCountInString(introducer); count == 0 || count > 2 {
+		panic("lexer: CommentIntroducer: introducer must be one or two runes")
+	}
+
+	return func(lexer *Lexer) {
+		lexer.commentIntro = introducer
+	}
+}
+
+// RouteComments routes Comment and DocComment tokens to the given
+// handler instead of the token stream, so NextToken never returns
+// them. This mirrors the inverse of go/scanner's ScanComments flag;
+// by default, comments stay inline in the stream.
+func RouteComments(handler func(token.Token)) Option {
+	return func(lexer *Lexer) {
+		lexer.commentHandler = handler
+	}
+}
+
+// Drop is a sentinel token type for Transform hooks: a hook which
+// returns a token of this type removes the token from the stream
+// entirely. The lexer itself never emits it.
+const Drop token.Type = -1
+
+// Transform registers a hook which is applied to every token before
+// it reaches the stream, letting embedders rewrite literals, remap
+// types, or drop tokens by returning one of type Drop, without
+// forking the lexer. Semicolon insertion is decided by the lexed
+// token, before the hook runs.
+func Transform(transform func(token.Token) token.Token) Option {
+	return func(lexer *Lexer) {
+		lexer.transform = transform
+	}
+}
+
+// InsertSemiAfter overrides the predicate deciding which token types
+// demand an automatically inserted semicolon at the newline which
+// follows them. The default is token.InsertSemiAfter, which covers
+// identifiers, literals, the break, continue, return, true, false,
+// and nil keywords, closing brackets, and the postfix ++ and --
+// operators; embedders adjust it to cover token types of their own,
+// like registered keywords.
+func InsertSemiAfter(predicate func(token.Type) bool) Option {
+	return func(lexer *Lexer) {
+		lexer.insertSemiAfter = predicate
+	}
+}
+
+// BlankLines makes the lexer emit a BlankLine token for every empty
+// line, i.e. for each newline directly following another, instead of
+// discarding it as whitespace. A REPL uses this to treat a blank
+// line as the end of the current input. By default, blank lines are
+// plain whitespace.
+func BlankLines() Option {
+	return func(lexer *Lexer) {
+		lexer.blankLines = true
+	}
+}
+
+// WithSource makes the lexer retain the exact source bytes of every
+// token, retrievable with Token.Source, even where the emitted
+// literal diverges from the source text, as under the Normalize
+// option. Each token carries its own copy of its bytes, roughly
+// doubling the memory held per token; formatters which must re-emit
+// the source faithfully pay that cost deliberately. By default, no
+// source bytes are retained.
+func WithSource() Option {
+	return func(lexer *Lexer) {
+		lexer.trackSource = true
+	}
+}
+
+// DetectMixedScripts makes the lexer raise ErrMixedScript for
+// identifiers which draw letters from more than one of the Latin,
+// Cyrillic, and Greek scripts, a hardening measure against homoglyph
+// spoofing. The warning is non-fatal: the identifier is still emitted
+// exactly as written. By default, no script check is performed.
+func DetectMixedScripts() Option {
+	return func(lexer *Lexer) {
+		lexer.mixedScript = true
+	}
+}
+
+// Normalize makes the lexer rewrite identifier literals to Unicode
+// normalization form C before emitting them, so visually identical
+// names with precomposed and decomposed spellings lex to the same
+// literal. Positions always span the raw source text. By default,
+// literals are emitted exactly as written.
+func Normalize() Option {
+	return func(lexer *Lexer) {
+		lexer.normalize = true
+	}
+}
+
+// Indentation makes the lexer treat leading whitespace as
+// significant, emitting an Indent token when a line is indented past
+// the one before it and matching Dedent tokens when the indentation
+// drops back, like Python. Blank and comment-only lines never change
+// the indentation, and any levels still open at the end of the source
+// are closed with Dedent tokens before EOF. By default, indentation
+// is not significant.
+func Indentation() Option {
+	return func(lexer *Lexer) {
+		lexer.indentation = true
+	}
+}
+
+// KeepWhitespace makes the lexer emit the whitespace between tokens
+// as Whitespace tokens instead of discarding it, so concatenating
+// every literal in the stream reconstructs the source byte for byte.
+// Formatters and refactoring tools use this for lossless round trips;
+// by default, whitespace is discarded.
+func KeepWhitespace() Option {
+	return func(lexer *Lexer) {
+		lexer.whitespace = true
+	}
+}
+
+// FoldCase makes the lexer match keywords case-insensitively using
+// Unicode simple case folding, so IF, If, and if all lex as the if
+// keyword. By default, keywords are matched case-sensitively.
+func FoldCase() Option {
+	return func(lexer *Lexer) {
+		lexer.foldCase = true
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kryptonlang/krypton/pkg/file"
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// Scanner lexes a krypton source synchronously: each NextToken call
+// drives the state machine inline and returns the next token, with
+// no background goroutine or token stream involved. It produces the
+// same token sequence as a Lexer on the same source, and is suited
+// to embedders which may stop reading tokens before reaching EOF.
+type Scanner struct {
+	lexer *Lexer
+}
+
+// NewScanner creates a new Scanner which tokenizes the given source.
+// The handler, if non-nil, is called with every error encountered
+// while lexing.
+func NewScanner(source io.Reader, handler ErrorHandler, options ...Option) *Scanner {
+	lexer := newLexer(source, handler, options...)
+	lexer.sync = true
+
+	return &Scanner{lexer: lexer}
+}
+
+// NextToken lexes and returns the next token from the source. Once
+// the source has been exhausted, it returns tokens of type EOF.
+func (scanner *Scanner) NextToken() token.Token {
+	lexer := scanner.lexer
+
+	for len(lexer.pending) == 0 && !lexer.done {
+		lexer.next()
+	}
+
+	if len(lexer.pending) == 0 {
+		return token.Token{Type: token.EOF, Pos: lexer.tokenStart, End: lexer.tokenStart}
+	}
+
+	tok := lexer.pending[0]
+	lexer.pending = lexer.pending[1:]
+	return tok
+}
+
+// Clone returns a snapshot of the scanner for speculative parsing:
+// the clone gets its own copies of the buffered tokens, position
+// markers, and semicolon insertion state, so the tokens buffered at
+// the time of the snapshot can be re-read from it after the original
+// advances.
+//
+// Both scanners still share the underlying source reader, so once
+// either of them lexes past the tokens buffered at the snapshot, the
+// other must not be advanced any further. Clone exists only on the
+// synchronous Scanner; the concurrent Lexer's goroutine owns its
+// state, which cannot be snapshotted from outside.
+func (scanner *Scanner) Clone() *Scanner {
+	lexer := *scanner.lexer
+
+	// the in-progress literal is always empty between NextToken
+	// calls, and a fresh builder keeps the copies from sharing one
+	lexer.tokenLiteral = strings.Builder{}
+	lexer.pending = append([]token.Token(nil), scanner.lexer.pending...)
+	lexer.unread = append([]token.Token(nil), scanner.lexer.unread...)
+
+	return &Scanner{lexer: &lexer}
+}
+
+// TokenizeAll lexes the given source in its entirety and returns
+// every token lexed from it, including the final EOF token, along
+// with all the errors encountered while lexing.
+func TokenizeAll(source io.Reader, options ...Option) ([]token.Token, []error) {
+	var errors []error
+	scanner := NewScanner(source, func(err error) {
+		errors = append(errors, err)
+	}, options...)
+
+	var tokens []token.Token
+	for {
+		tok := scanner.NextToken()
+		tokens = append(tokens, tok)
+
+		if tok.Type == token.EOF {
+			return tokens, errors
+		}
+	}
+}
+
+// TokenizeSet lexes every file of the given set in order and returns
+// each file's tokens, including its final EOF token, along with the
+// errors encountered across the whole set. Every file is lexed with
+// its own Scanner, so lines and columns restart per file, and errors
+// resolve to name:line:column strings qualified with the name of the
+// file they were raised in.
+func TokenizeSet(set *file.FileSet, options ...Option) ([][]token.Token, []error) {
+	var errors []error
+
+	tokens := make([][]token.Token, 0, len(set.Files()))
+	for _, source := range set.Files() {
+		scanner := NewScanner(source.Source(), func(err error) {
+			errors = append(errors, err)
+		}, options...)
+		scanner.lexer.file = source
+
+		var fileTokens []token.Token
+		for {
+			tok := scanner.NextToken()
+			fileTokens = append(fileTokens, tok)
+
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+
+		tokens = append(tokens, fileTokens)
+	}
+
+	return tokens, errors
+}
+
+// Validate lexes the given source in its entirety without
+// materializing the tokens and returns the errors encountered; a nil
+// slice means the source lexes cleanly. Linters use this to check a
+// source without wiring up a handler and drain loop themselves.
+func Validate(source io.Reader, options ...Option) []error {
+	var errors []error
+	scanner := NewScanner(source, func(err error) {
+		errors = append(errors, err)
+	}, options...)
+
+	for scanner.NextToken().Type != token.EOF {
+	}
+
+	return errors
+}
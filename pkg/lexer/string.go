@@ -0,0 +1,172 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lexer
+
+import (
+	"unicode/utf8"
+
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// lexString lexes a string literal token. The emitted literal
+// includes the surrounding quotes and unresolved escape sequences.
+func (lexer *Lexer) lexString() {
+	lexer.consume() // opening quote
+
+	for lexer.current != '"' {
+		switch lexer.current {
+		case eof, '\n':
+			lexer.raise(ErrUnclosedStringLit)
+			lexer.emit(token.String)
+			return
+		case '\\':
+			lexer.consume() // the backslash
+
+			if lexer.current == '\n' {
+				// a backslash directly before a newline is a line
+				// continuation, not an unterminated string
+				lexer.consume()
+			} else {
+				lexer.consumeEscape()
+			}
+		default:
+			lexer.consume()
+		}
+	}
+
+	lexer.consume() // closing quote
+	lexer.emit(token.String)
+}
+
+// lexRune lexes a rune literal token, which must contain exactly one
+// character. The emitted literal includes the surrounding quotes and
+// unresolved escape sequences.
+func (lexer *Lexer) lexRune() {
+	lexer.consume() // opening quote
+
+	chars := 0
+	for lexer.current != '\'' {
+		switch lexer.current {
+		case eof, '\n':
+			lexer.raise(ErrUnclosedRuneLit)
+			lexer.emit(token.Rune)
+			return
+		case '\\':
+			lexer.consume() // the backslash
+			lexer.consumeEscape()
+		default:
+			lexer.consume()
+		}
+
+		chars++
+	}
+
+	lexer.consume() // closing quote
+
+	switch {
+	case chars == 0:
+		lexer.raise(ErrEmptyRuneLiteral)
+	case chars > 1:
+		lexer.raise(ErrTooManyRuneChars)
+	}
+
+	lexer.emit(token.Rune)
+}
+
+// consumeEscape consumes and validates a single escape sequence
+// whose leading backslash has already been consumed. The escape is
+// consumed raw: resolving it into the character it represents is
+// left to the consumer of the literal.
+//
+// The recognized escapes are the single-character escapes \a, \b,
+// \e (ESC), \f, \n, \r, \t, \v, \\, \', and \", octal escapes of
+// the form \NNN, and the \xNN, \uNNNN, and \UNNNNNNNN hex escapes.
+func (lexer *Lexer) consumeEscape() {
+	switch lexer.current {
+	case 'a', 'b', 'e', 'f', 'n', 'r', 't', 'v', '\\', '\'', '"':
+		lexer.consume()
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		lexer.consumeOctalDigits(3)
+	case 'x':
+		lexer.consume()
+		lexer.consumeHexDigits(2, false)
+	case 'u':
+		lexer.consume()
+		lexer.consumeHexDigits(4, true)
+	case 'U':
+		lexer.consume()
+		lexer.consumeHexDigits(8, true)
+	default:
+		lexer.raise(ErrIllegalEscape)
+	}
+}
+
+// consumeOctalDigits consumes exactly count octal digits, validating
+// that they encode a value which fits in a byte.
+func (lexer *Lexer) consumeOctalDigits(count int) {
+	value := 0
+	for i := 0; i < count; i++ {
+		if !token.IsDigit(lexer.current, 8) {
+			lexer.raise(ErrIllegalEscape)
+			return
+		}
+
+		value = value*8 + digitValue(lexer.current)
+		lexer.consume()
+	}
+
+	if value > 0xFF {
+		lexer.raise(ErrOctalEscapeRange)
+	}
+}
+
+// consumeHexDigits consumes exactly count hexadecimal digits,
+// optionally validating that they encode a legal Unicode codepoint.
+func (lexer *Lexer) consumeHexDigits(count int, validate bool) {
+	value := 0
+	for i := 0; i < count; i++ {
+		if !token.IsDigit(lexer.current, 16) {
+			lexer.raise(ErrIllegalEscape)
+			return
+		}
+
+		value = value*16 + digitValue(lexer.current)
+		lexer.consume()
+	}
+
+	if validate && !utf8.ValidRune(rune(value)) {
+		// surrogate halves get their own diagnostic, since writing
+		// astral characters as a \u escape pair is a common habit
+		// carried over from UTF-16 based languages
+		if value >= 0xD800 && value <= 0xDFFF {
+			lexer.raise(ErrSurrogateEscape)
+			return
+		}
+
+		lexer.raise(ErrInvalidCodepoint)
+	}
+}
+
+// digitValue returns the numeric value of the given digit rune.
+func digitValue(char rune) int {
+	switch {
+	case char >= '0' && char <= '9':
+		return int(char - '0')
+	case char >= 'a' && char <= 'f':
+		return int(char-'a') + 10
+	case char >= 'A' && char <= 'F':
+		return int(char-'A') + 10
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lsp converts krypton token streams into the wire
+// representations used by the Language Server Protocol.
+package lsp
+
+import (
+	"unicode/utf8"
+
+	"github.com/kryptonlang/krypton/pkg/token"
+)
+
+// Legend is the semantic token type legend announced by a language
+// server using this package: the type index encoded for a token is
+// the index of its name in this slice, as the protocol's
+// SemanticTokensLegend expects.
+var Legend = []string{
+	"variable", "keyword", "comment", "string", "number", "operator",
+}
+
+// The semantic token type indices, matching Legend.
+const (
+	typeVariable uint32 = iota
+	typeKeyword
+	typeComment
+	typeString
+	typeNumber
+	typeOperator
+)
+
+// SemanticTokens encodes the given tokens as LSP semantic token
+// data: five values per token, holding the delta-encoded line and
+// start character, the length, the type index into Legend, and the
+// modifier bitset, which is always zero. Lines and characters are
+// zero-based, per the protocol, while token positions are one-based.
+// Tokens with no semantic class, like whitespace, inserted
+// semicolons, and EOF, are skipped.
+func SemanticTokens(tokens []token.Token) []uint32 {
+	var data []uint32
+	var prevLine, prevChar uint32
+
+	for _, tok := range tokens {
+		typeIndex, ok := classOf(tok)
+		if !ok {
+			continue
+		}
+
+		line := uint32(tok.Pos.Line - 1)
+		char := uint32(tok.Pos.Column - 1)
+
+		deltaLine := line - prevLine
+		deltaChar := char
+		if deltaLine == 0 {
+			deltaChar = char - prevChar
+		}
+
+		data = append(data, deltaLine, deltaChar, length(tok), typeIndex, 0)
+		prevLine, prevChar = line, char
+	}
+
+	return data
+}
+
+// length returns the length of the token in characters. Tokens which
+// span a single line are measured by their end position, so the
+// length honors the lexer's TabWidth option; for multi-line tokens,
+// which the protocol cannot represent exactly, the rune count of the
+// literal is the best approximation.
+func length(tok token.Token) uint32 {
+	if tok.End.Line == tok.Pos.Line {
+		return uint32(tok.End.Column - tok.Pos.Column)
+	}
+
+	return uint32(utf8.RuneCountInString(tok.Literal))
+}
+
+// classOf returns the semantic token type index of the given token,
+// reporting false for tokens which have none.
+func classOf(tok token.Token) (uint32, bool) {
+	switch {
+	case tok.Type == token.Comment || tok.Type == token.DocComment:
+		return typeComment, true
+
+	case tok.Type == token.Identifier:
+		return typeVariable, true
+	case tok.Type == token.String || tok.Type == token.Rune:
+		return typeString, true
+	case token.IsLiteral(tok.Type):
+		return typeNumber, true
+
+	// keywords and operators always have their canonical
+	// representation as their literal, so the string-based
+	// classifiers apply. Inserted semicolons keep their newline
+	// literal and are skipped along with whitespace.
+	case token.IsKeyword(tok.Literal):
+		return typeKeyword, true
+	case token.IsOperator(tok.Literal):
+		return typeOperator, true
+
+	default:
+		return 0, false
+	}
+}
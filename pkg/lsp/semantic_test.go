@@ -0,0 +1,47 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kryptonlang/krypton/pkg/lexer"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	tokens, errs := lexer.TokenizeAll(strings.NewReader("let x = 42 # hi\ns"))
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	expected := []uint32{
+		0, 0, 3, typeKeyword, 0, // let
+		0, 4, 1, typeVariable, 0, // x
+		0, 2, 1, typeOperator, 0, // =
+		0, 2, 2, typeNumber, 0, // 42
+		0, 3, 4, typeComment, 0, // # hi
+		1, 0, 1, typeVariable, 0, // s
+	}
+
+	data := SemanticTokens(tokens)
+	if len(data) != len(expected) {
+		t.Fatalf("encoded %d values, expected %d: %v", len(data), len(expected), data)
+	}
+
+	for i, value := range data {
+		if value != expected[i] {
+			t.Errorf("value %d: got %d, expected %d", i, value, expected[i])
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+// Category is the broad classification of a token type, as used by
+// syntax highlighters and editor tooling which only care whether a
+// token is a keyword, operator, literal, or comment rather than
+// which one it is.
+type Category int
+
+// The categories a token type can belong to. CategorySpecial covers
+// the types outside the classification ranges: EOF, Illegal, and
+// layout tokens like Whitespace, Indent, and BlankLine.
+const (
+	CategorySpecial Category = iota
+	CategoryLiteral
+	CategoryKeyword
+	CategoryOperator
+	CategoryComment
+)
+
+// String returns the name of the category, like "keyword".
+func (category Category) String() string {
+	switch category {
+	case CategoryLiteral:
+		return "literal"
+	case CategoryKeyword:
+		return "keyword"
+	case CategoryOperator:
+		return "operator"
+	case CategoryComment:
+		return "comment"
+	default:
+		return "special"
+	}
+}
+
+// Category returns the category of the token type. Literals,
+// keywords, and operators are classified by the same ranges backing
+// IsLiteral, IsKeyword, and IsOperator, with keywords added through
+// RegisterKeyword included; Comment and DocComment classify as
+// CategoryComment, and every remaining type as CategorySpecial.
+func (tokenType Type) Category() Category {
+	switch {
+	case IsLiteral(tokenType):
+		return CategoryLiteral
+	case isKeyword(tokenType):
+		return CategoryKeyword
+	case isOperator(tokenType):
+		return CategoryOperator
+	case tokenType == Comment || tokenType == DocComment:
+		return CategoryComment
+	default:
+		return CategorySpecial
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// The various errors returned while unquoting literals.
+var (
+	ErrBadQuotes        = errors.New("literal is not surrounded by quotes")
+	ErrInvalidEscape    = errors.New("invalid escape sequence in literal")
+	ErrInvalidCodepoint = errors.New("escape sequence is invalid Unicode codepoint")
+	ErrEmptyRune        = errors.New("empty rune literal")
+	ErrTooManyRunes     = errors.New("too many characters in rune literal")
+)
+
+// UnquoteString resolves a string literal, as lexed by the lexer,
+// into the string it represents: the surrounding double quotes are
+// stripped and every escape sequence the lexer recognizes is
+// resolved. Escapes the lexer would itself reject return an error.
+func UnquoteString(literal string) (string, error) {
+	if len(literal) < 2 || literal[0] != '"' || literal[len(literal)-1] != '"' {
+		return "", ErrBadQuotes
+	}
+
+	var builder strings.Builder
+
+	rest := literal[1 : len(literal)-1]
+	for len(rest) > 0 {
+		if rest[0] != '\\' {
+			_, size := utf8.DecodeRuneInString(rest)
+			builder.WriteString(rest[:size])
+			rest = rest[size:]
+			continue
+		}
+
+		resolved, remaining, err := resolveEscape(rest)
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteString(resolved)
+		rest = remaining
+	}
+
+	return builder.String(), nil
+}
+
+// UnquoteRune resolves a rune literal, as lexed by the lexer, into
+// the rune it represents: the surrounding single quotes are stripped
+// and any escape sequence is resolved. A literal which contains no
+// characters, or more than one, returns an error, mirroring the
+// rules enforced by the lexer.
+func UnquoteRune(literal string) (rune, error) {
+	if len(literal) < 2 || literal[0] != '\'' || literal[len(literal)-1] != '\'' {
+		return 0, ErrBadQuotes
+	}
+
+	contents := literal[1 : len(literal)-1]
+	switch {
+	case len(contents) == 0:
+		return 0, ErrEmptyRune
+
+	case contents[0] == '\\' && len(contents) > 1:
+		switch contents[1] {
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			value, rest, err := resolveOctalDigits(contents[1:], 3)
+			switch {
+			case err != nil:
+				return 0, err
+			case rest != "":
+				return 0, ErrTooManyRunes
+			}
+
+			return rune(value), nil
+
+		case 'x', 'u', 'U':
+			// hex escapes represent a codepoint, not utf-8 bytes
+			counts := map[byte]int{'x': 2, 'u': 4, 'U': 8}
+
+			value, rest, err := resolveHexDigits(contents[2:], counts[contents[1]])
+			switch {
+			case err != nil:
+				return 0, err
+			case rest != "":
+				return 0, ErrTooManyRunes
+			case !utf8.ValidRune(rune(value)):
+				return 0, ErrInvalidCodepoint
+			}
+
+			return rune(value), nil
+
+		default:
+			resolved, rest, err := resolveEscape(contents)
+			switch {
+			case err != nil:
+				return 0, err
+			case rest != "":
+				return 0, ErrTooManyRunes
+			case resolved == "":
+				// a line continuation is not a character
+				return 0, ErrInvalidEscape
+			}
+
+			char, _ := utf8.DecodeRuneInString(resolved)
+			return char, nil
+		}
+
+	default:
+		char, size := utf8.DecodeRuneInString(contents)
+		if size != len(contents) {
+			return 0, ErrTooManyRunes
+		}
+
+		return char, nil
+	}
+}
+
+// CommentText returns the text of a comment literal with its
+// delimiters stripped: the leading # or ## markers of a line comment,
+// the // or /// of its slash-introduced counterpart, or the
+// surrounding /* and */ of a block comment, along with a single space
+// of padding inside them. The raw literal, delimiters included,
+// remains available on the token itself.
+func CommentText(literal string) string {
+	switch {
+	case strings.HasPrefix(literal, "##"):
+		literal = literal[2:]
+	case strings.HasPrefix(literal, "#"):
+		literal = literal[1:]
+	case strings.HasPrefix(literal, "///"):
+		literal = literal[3:]
+	case strings.HasPrefix(literal, "/*"):
+		literal = strings.TrimSuffix(literal[2:], "*/")
+		literal = strings.TrimSuffix(literal, " ")
+	case strings.HasPrefix(literal, "//"):
+		literal = literal[2:]
+	}
+
+	return strings.TrimPrefix(literal, " ")
+}
+
+// resolveEscape resolves the escape sequence at the start of the
+// given string, returning the text it represents along with the
+// remainder of the string. The accepted escapes mirror exactly what
+// the lexer's consumeEscape accepts.
+func resolveEscape(literal string) (string, string, error) {
+	if len(literal) < 2 {
+		return "", "", ErrInvalidEscape
+	}
+
+	switch char := literal[1]; char {
+	case '\n':
+		// line continuation: neither the backslash nor the newline
+		// are part of the content
+		return "", literal[2:], nil
+	case 'a':
+		return "\a", literal[2:], nil
+	case 'b':
+		return "\b", literal[2:], nil
+	case 'e':
+		// ESC, for ANSI terminal sequences
+		return "\x1b", literal[2:], nil
+	case 'f':
+		return "\f", literal[2:], nil
+	case 'n':
+		return "\n", literal[2:], nil
+	case 'r':
+		return "\r", literal[2:], nil
+	case 't':
+		return "\t", literal[2:], nil
+	case 'v':
+		return "\v", literal[2:], nil
+	case '\\':
+		return "\\", literal[2:], nil
+	case '\'':
+		return "'", literal[2:], nil
+	case '"':
+		return "\"", literal[2:], nil
+
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		value, rest, err := resolveOctalDigits(literal[1:], 3)
+		return string([]byte{byte(value)}), rest, err
+
+	case 'x':
+		value, rest, err := resolveHexDigits(literal[2:], 2)
+		return string([]byte{byte(value)}), rest, err
+
+	case 'u':
+		value, rest, err := resolveHexDigits(literal[2:], 4)
+		if err == nil && !utf8.ValidRune(rune(value)) {
+			err = ErrInvalidCodepoint
+		}
+
+		return string(rune(value)), rest, err
+
+	case 'U':
+		value, rest, err := resolveHexDigits(literal[2:], 8)
+		if err == nil && !utf8.ValidRune(rune(value)) {
+			err = ErrInvalidCodepoint
+		}
+
+		return string(rune(value)), rest, err
+
+	default:
+		return "", "", ErrInvalidEscape
+	}
+}
+
+// resolveOctalDigits resolves exactly count octal digits at the
+// start of the given string into their value, returning it along
+// with the remainder of the string. Values which do not fit in a
+// byte return an error.
+func resolveOctalDigits(literal string, count int) (int, string, error) {
+	if len(literal) < count {
+		return 0, "", ErrInvalidEscape
+	}
+
+	value := 0
+	for i := 0; i < count; i++ {
+		digit := digitValue(rune(literal[i]))
+		if digit < 0 || digit > 7 {
+			return 0, "", ErrInvalidEscape
+		}
+
+		value = value*8 + digit
+	}
+
+	if value > 0xFF {
+		return 0, "", ErrInvalidEscape
+	}
+
+	return value, literal[count:], nil
+}
+
+// resolveHexDigits resolves exactly count hexadecimal digits at the
+// start of the given string into their value, returning it along
+// with the remainder of the string.
+func resolveHexDigits(literal string, count int) (int, string, error) {
+	if len(literal) < count {
+		return 0, "", ErrInvalidEscape
+	}
+
+	value := 0
+	for i := 0; i < count; i++ {
+		digit := digitValue(rune(literal[i]))
+		if digit < 0 || digit > 15 {
+			return 0, "", ErrInvalidEscape
+		}
+
+		value = value*16 + digit
+	}
+
+	return value, literal[count:], nil
+}
+
+// digitValue returns the numeric value of the given digit rune, or
+// -1 if the rune is not a digit.
+func digitValue(char rune) int {
+	switch {
+	case char >= '0' && char <= '9':
+		return int(char - '0')
+	case char >= 'a' && char <= 'z':
+		return int(char-'a') + 10
+	case char >= 'A' && char <= 'Z':
+		return int(char-'A') + 10
+	default:
+		return -1
+	}
+}
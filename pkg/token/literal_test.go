@@ -0,0 +1,147 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import "testing"
+
+func TestUnquoteString(t *testing.T) {
+	cases := []struct {
+		literal  string
+		expected string
+	}{
+		{`""`, ""},
+		{`"hello"`, "hello"},
+		{`"a\nb\tc"`, "a\nb\tc"},
+		{`"\"quoted\""`, `"quoted"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\e[0m"`, "\x1b[0m"},
+		{`"\x41\x42"`, "AB"},
+		{`"\101\102"`, "AB"},
+		{`"é"`, "é"},
+		{"\"ab\\\ncd\"", "abcd"},
+		{`"\U0001F600"`, "😀"},
+		{`"unicode: 😀"`, "unicode: 😀"},
+	}
+
+	for _, c := range cases {
+		unquoted, err := UnquoteString(c.literal)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.literal, err)
+			continue
+		}
+
+		if unquoted != c.expected {
+			t.Errorf("%q: unquoted to %q, expected %q", c.literal, unquoted, c.expected)
+		}
+	}
+}
+
+func TestCommentText(t *testing.T) {
+	cases := []struct {
+		literal  string
+		expected string
+	}{
+		{"# hello", "hello"},
+		{"#hello", "hello"},
+		{"## documentation", "documentation"},
+		{"#", ""},
+		{"##", ""},
+		{"#  double space", " double space"},
+		{"// note", "note"},
+		{"//note", "note"},
+		{"/// documentation", "documentation"},
+		{"//", ""},
+		{"///", ""},
+		{"/* block */", "block"},
+		{"/*block*/", "block"},
+	}
+
+	for _, c := range cases {
+		if text := CommentText(c.literal); text != c.expected {
+			t.Errorf("%q: got %q, expected %q", c.literal, text, c.expected)
+		}
+	}
+}
+
+func TestUnquoteRune(t *testing.T) {
+	cases := []struct {
+		literal  string
+		expected rune
+	}{
+		{`'a'`, 'a'},
+		{`'\n'`, '\n'},
+		{`'\''`, '\''},
+		{`'\\'`, '\\'},
+		{`'\x41'`, 'A'},
+		{`'\101'`, 'A'},
+		{`'\e'`, 0x1b},
+		{`'\xFF'`, 0xFF},
+		{`'é'`, 'é'},
+		{`'\U0001F600'`, '😀'},
+		{`'😀'`, '😀'},
+	}
+
+	for _, c := range cases {
+		char, err := UnquoteRune(c.literal)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.literal, err)
+			continue
+		}
+
+		if char != c.expected {
+			t.Errorf("%q: unquoted to %q, expected %q", c.literal, char, c.expected)
+		}
+	}
+}
+
+func TestUnquoteRuneErrors(t *testing.T) {
+	cases := []struct {
+		literal  string
+		expected error
+	}{
+		{`''`, ErrEmptyRune},
+		{`'ab'`, ErrTooManyRunes},
+		{`'\n\t'`, ErrTooManyRunes},
+		{`'a`, ErrBadQuotes},
+		{`a`, ErrBadQuotes},
+		{`'\q'`, ErrInvalidEscape},
+		{`'\uD800'`, ErrInvalidCodepoint},
+	}
+
+	for _, c := range cases {
+		if _, err := UnquoteRune(c.literal); err != c.expected {
+			t.Errorf("%q: got error %v, expected %v", c.literal, err, c.expected)
+		}
+	}
+}
+
+func TestUnquoteStringErrors(t *testing.T) {
+	cases := []string{
+		`"`,
+		`no quotes`,
+		`"unterminated`,
+		`"\q"`,
+		`"\x4"`,
+		`"\xgg"`,
+		`"\777"`,
+		`"\12"`,
+		`"\uD800..."`,
+		`"\"`,
+	}
+
+	for _, literal := range cases {
+		if _, err := UnquoteString(literal); err == nil {
+			t.Errorf("%q: expected an error", literal)
+		}
+	}
+}
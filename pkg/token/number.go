@@ -0,0 +1,57 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseNumber decodes a numeric literal, as lexed by the lexer, into
+// its value. Integers are returned as an int64, or as a uint64 when
+// the value does not fit in an int64, with isFloat set to false.
+// Floats, identified by a fractional part or an exponent, are
+// returned as a float64 with isFloat set to true. All the bases,
+// exponents, and digit separators accepted by the lexer are handled,
+// and values which overflow their type return an error.
+func ParseNumber(literal string) (value any, isFloat bool, err error) {
+	literal = strings.ReplaceAll(literal, "_", "")
+
+	if isFloatLiteral(literal) {
+		value, err := strconv.ParseFloat(literal, 64)
+		return value, true, err
+	}
+
+	// ParseInt with base 0 handles the 0x, 0o, and 0b prefixes, as
+	// well as legacy octal literals with a plain leading 0
+	if value, err := strconv.ParseInt(literal, 0, 64); err == nil {
+		return value, false, nil
+	}
+
+	value, err = strconv.ParseUint(literal, 0, 64)
+	return value, false, err
+}
+
+// isFloatLiteral reports whether the given numeric literal has a
+// fractional part or an exponent.
+func isFloatLiteral(literal string) bool {
+	if strings.ContainsRune(literal, '.') {
+		return true
+	}
+
+	if strings.HasPrefix(literal, "0x") || strings.HasPrefix(literal, "0X") {
+		return strings.ContainsAny(literal, "pP")
+	}
+
+	return strings.ContainsAny(literal, "eE")
+}
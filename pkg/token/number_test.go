@@ -0,0 +1,57 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import "testing"
+
+func TestParseNumber(t *testing.T) {
+	cases := []struct {
+		literal string
+		value   any
+		isFloat bool
+	}{
+		{"42", int64(42), false},
+		{"0xFF", int64(255), false},
+		{"0o17", int64(15), false},
+		{"0b101", int64(5), false},
+		{"0123", int64(83), false},
+		{"1_000_000", int64(1000000), false},
+		{"18446744073709551615", uint64(18446744073709551615), false},
+		{"3.14", 3.14, true},
+		{"1e3", 1000.0, true},
+		{"2E-3", 0.002, true},
+		{"0x1.8p3", 12.0, true},
+		{"0x1p-2", 0.25, true},
+	}
+
+	for _, c := range cases {
+		value, isFloat, err := ParseNumber(c.literal)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.literal, err)
+			continue
+		}
+
+		if value != c.value || isFloat != c.isFloat {
+			t.Errorf(
+				"%q: parsed to %v (float: %v), expected %v (float: %v)",
+				c.literal, value, isFloat, c.value, c.isFloat,
+			)
+		}
+	}
+}
+
+func TestParseNumberOverflow(t *testing.T) {
+	if _, _, err := ParseNumber("99999999999999999999999999"); err == nil {
+		t.Error("expected an overflow error")
+	}
+}
@@ -0,0 +1,578 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package token defines the lexical tokens of the krypton
+// programming language, along with utility functions for working
+// with them.
+package token
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kryptonlang/krypton/pkg/file"
+)
+
+// Token represents a single lexical token of krypton source code.
+// Tokens marshal to a stable JSON representation, with the type
+// rendered as its canonical name.
+type Token struct {
+	Type    Type     `json:"type"`    // type of the token
+	Literal string   `json:"literal"` // raw literal of the token
+	Pos     file.Pos `json:"pos"`     // position of the token's first character
+	End     file.Pos `json:"end"`     // position just past the token's last character
+
+	source []byte // exact source bytes of the token, if retained
+}
+
+// Source returns the exact source bytes of the token, as retained by
+// the lexer's WithSource option. It returns nil when the option was
+// not in effect, and for synthesized tokens like inserted semicolons
+// and EOF, which occupy no source bytes.
+func (t Token) Source() []byte {
+	return t.source
+}
+
+// WithSource returns a copy of the token carrying the given raw
+// source bytes, retrievable with Source.
+func (t Token) WithSource(source []byte) Token {
+	t.source = source
+	return t
+}
+
+// String returns a human-readable representation of the token, like
+// IDENT("foo") @ 3:5. The parenthesized literal is omitted for
+// tokens like keywords and operators whose literal is already their
+// type's canonical representation.
+func (t Token) String() string {
+	if t.Literal == t.Type.String() {
+		return fmt.Sprintf("%v @ %v", t.Type, t.Pos)
+	}
+
+	return fmt.Sprintf("%v(%q) @ %v", t.Type, t.Literal, t.Pos)
+}
+
+// Equal reports whether the token has the same type and literal as
+// other. Unless ignorePos is true, the tokens' positions must match
+// as well.
+func (t Token) Equal(other Token, ignorePos bool) bool {
+	if t.Type != other.Type || t.Literal != other.Literal {
+		return false
+	}
+
+	return ignorePos || t.Pos == other.Pos && t.End == other.End
+}
+
+// Range returns the span of the source covered by the token.
+func (t Token) Range() file.Range {
+	return file.Range{Start: t.Pos, End: t.End}
+}
+
+// Type represents the type of a lexical token.
+type Type int
+
+// The types of tokens which occur in krypton source code. The
+// unexported types delimit the ranges used by the classification
+// functions and are never emitted by the lexer.
+const (
+	EOF Type = iota
+	Illegal
+	Comment
+	DocComment
+	Whitespace
+	Indent
+	Dedent
+	BlankLine
+
+	literalBeg
+	Identifier
+	Int
+	Float
+	Imaginary
+	String
+	Rune
+	literalEnd
+
+	keywordBeg
+	As
+	Break
+	Case
+	Const
+	Continue
+	Default
+	Else
+	False
+	For
+	Func
+	If
+	Import
+	In
+	Let
+	Match
+	Module
+	Nil
+	Package
+	Return
+	Switch
+	True
+	While
+	keywordEnd
+
+	// The lexer lexes operators greedily: runes keep getting
+	// consumed while the literal built so far remains a valid
+	// operator. For that to work, every contiguous prefix of a
+	// multi-rune operator must itself be a valid operator.
+	operatorBeg
+	LeftParen        // (
+	RightParen       // )
+	LeftBracket      // [
+	RightBracket     // ]
+	LeftBrace        // {
+	RightBrace       // }
+	Comma            // ,
+	Period           // .
+	Semicolon        // ;
+	Colon            // :
+	DotDot           // ..
+	DotDotDot        // ...
+	Question         // ?
+	QuestionQuestion // ??
+	QuestionPeriod   // ?.
+	At               // @
+
+	Plus       // +
+	Minus      // -
+	Star       // *
+	Slash      // /
+	Percent    // %
+	PlusPlus   // ++
+	MinusMinus // --
+	StarStar   // **
+	Arrow      // ->
+
+	Amp        // &
+	Bar        // |
+	Caret      // ^
+	Tilde      // ~
+	AmpAmp     // &&
+	BarBar     // ||
+	BarGreater // |>
+
+	LessLess       // <<
+	GreaterGreater // >>
+
+	Equal        // =
+	Bang         // !
+	EqualEqual   // ==
+	BangEqual    // !=
+	Less         // <
+	Greater      // >
+	LessEqual    // <=
+	GreaterEqual // >=
+
+	PlusEqual             // +=
+	MinusEqual            // -=
+	StarEqual             // *=
+	SlashEqual            // /=
+	PercentEqual          // %=
+	AmpEqual              // &=
+	BarEqual              // |=
+	CaretEqual            // ^=
+	LessLessEqual         // <<=
+	GreaterGreaterEqual   // >>=
+	StarStarEqual         // **=
+	AmpAmpEqual           // &&=
+	BarBarEqual           // ||=
+	QuestionQuestionEqual // ??=
+	operatorEnd
+)
+
+// typeToString maps each token type to its canonical string
+// representation.
+var typeToString = map[Type]string{
+	EOF:        "EOF",
+	Illegal:    "ILLEGAL",
+	Comment:    "COMMENT",
+	DocComment: "DOC_COMMENT",
+	Whitespace: "WHITESPACE",
+	Indent:     "INDENT",
+	Dedent:     "DEDENT",
+	BlankLine:  "BLANK_LINE",
+
+	Identifier: "IDENT",
+	Int:        "INT",
+	Float:      "FLOAT",
+	Imaginary:  "IMAG",
+	String:     "STRING",
+	Rune:       "RUNE",
+
+	As:       "as",
+	Break:    "break",
+	Case:     "case",
+	Const:    "const",
+	Continue: "continue",
+	Default:  "default",
+	Else:     "else",
+	False:    "false",
+	For:      "for",
+	Func:     "func",
+	If:       "if",
+	Import:   "import",
+	In:       "in",
+	Let:      "let",
+	Match:    "match",
+	Module:   "module",
+	Nil:      "nil",
+	Package:  "package",
+	Return:   "return",
+	Switch:   "switch",
+	True:     "true",
+	While:    "while",
+
+	LeftParen:        "(",
+	RightParen:       ")",
+	LeftBracket:      "[",
+	RightBracket:     "]",
+	LeftBrace:        "{",
+	RightBrace:       "}",
+	Comma:            ",",
+	Period:           ".",
+	Semicolon:        ";",
+	Colon:            ":",
+	DotDot:           "..",
+	DotDotDot:        "...",
+	Question:         "?",
+	QuestionQuestion: "??",
+	QuestionPeriod:   "?.",
+	At:               "@",
+
+	Plus:       "+",
+	Minus:      "-",
+	Star:       "*",
+	Slash:      "/",
+	Percent:    "%",
+	PlusPlus:   "++",
+	MinusMinus: "--",
+	StarStar:   "**",
+	Arrow:      "->",
+
+	Amp:        "&",
+	Bar:        "|",
+	Caret:      "^",
+	Tilde:      "~",
+	AmpAmp:     "&&",
+	BarBar:     "||",
+	BarGreater: "|>",
+
+	LessLess:       "<<",
+	GreaterGreater: ">>",
+
+	Equal:        "=",
+	Bang:         "!",
+	EqualEqual:   "==",
+	BangEqual:    "!=",
+	Less:         "<",
+	Greater:      ">",
+	LessEqual:    "<=",
+	GreaterEqual: ">=",
+
+	PlusEqual:             "+=",
+	MinusEqual:            "-=",
+	StarEqual:             "*=",
+	SlashEqual:            "/=",
+	PercentEqual:          "%=",
+	AmpEqual:              "&=",
+	BarEqual:              "|=",
+	CaretEqual:            "^=",
+	LessLessEqual:         "<<=",
+	GreaterGreaterEqual:   ">>=",
+	StarStarEqual:         "**=",
+	AmpAmpEqual:           "&&=",
+	BarBarEqual:           "||=",
+	QuestionQuestionEqual: "??=",
+}
+
+// stringToType maps each canonical string representation back to its
+// token type. It is populated from typeToString during init.
+var stringToType = map[string]Type{}
+
+// foldedToType maps the case-folded literal of each keyword to its
+// token type, for case-insensitive lookups with LookupFold.
+var foldedToType = map[string]Type{}
+
+// registeredEnd delimits the range of token types handed out by
+// RegisterKeyword, which starts directly after operatorEnd.
+var registeredEnd = operatorEnd + 1
+
+func init() {
+	for tokenType, str := range typeToString {
+		stringToType[str] = tokenType
+
+		if isKeyword(tokenType) {
+			foldedToType[foldCase(str)] = tokenType
+		}
+	}
+}
+
+// String returns the canonical string representation of the token
+// type, like "IDENT" or "+=".
+func (tokenType Type) String() string {
+	return typeToString[tokenType]
+}
+
+// MarshalText implements encoding.TextMarshaler, rendering the token
+// type as its canonical string representation.
+func (tokenType Type) MarshalText() ([]byte, error) {
+	return []byte(tokenType.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the
+// canonical string representation of a token type. Strings which do
+// not name a token type are an error.
+func (tokenType *Type) UnmarshalText(text []byte) error {
+	parsed, ok := stringToType[string(text)]
+	if !ok {
+		return fmt.Errorf("token: unknown token type %q", text)
+	}
+
+	*tokenType = parsed
+	return nil
+}
+
+// NewTokenType returns the token type whose canonical string
+// representation is the given string, or Illegal if no type has that
+// representation: an unknown string must surface as an illegal
+// token, never masquerade as the zero-valued EOF.
+func NewTokenType(str string) Type {
+	if tokenType, ok := stringToType[str]; ok {
+		return tokenType
+	}
+
+	return Illegal
+}
+
+// RegisterKeyword registers the given literal as a new keyword and
+// returns its token type. The lexer picks registered keywords up
+// through Lookup, so registration must happen before lexing begins.
+// RegisterKeyword panics if the literal is already a token.
+func RegisterKeyword(literal string) Type {
+	if _, ok := stringToType[literal]; ok {
+		panic("token: RegisterKeyword: " + strconv.Quote(literal) + " is already a token")
+	}
+
+	tokenType := registeredEnd
+	registeredEnd++
+
+	typeToString[tokenType] = literal
+	stringToType[literal] = tokenType
+	foldedToType[foldCase(literal)] = tokenType
+	return tokenType
+}
+
+// Lookup returns the keyword type associated with the given
+// identifier, or Identifier if it is not a keyword.
+func Lookup(ident string) Type {
+	if tokenType, ok := stringToType[ident]; ok && isKeyword(tokenType) {
+		return tokenType
+	}
+
+	return Identifier
+}
+
+// LookupFold is like Lookup, but matches keywords case-insensitively
+// using Unicode simple case folding: LookupFold("IF") and
+// LookupFold("If") both return the If keyword. Identifiers whose
+// runes have no simple fold onto a keyword's runes, like "İf", stay
+// identifiers.
+func LookupFold(ident string) Type {
+	if tokenType, ok := foldedToType[foldCase(ident)]; ok {
+		return tokenType
+	}
+
+	return Identifier
+}
+
+// foldCase maps every rune of the given string to the smallest rune
+// in its simple case-folding orbit, yielding a canonical form under
+// which case-insensitive comparison becomes plain equality.
+func foldCase(str string) string {
+	return strings.Map(func(char rune) rune {
+		folded := char
+		for next := unicode.SimpleFold(char); next != char; next = unicode.SimpleFold(next) {
+			if next < folded {
+				folded = next
+			}
+		}
+
+		return folded
+	}, str)
+}
+
+// IsKeyword reports whether the given string is a krypton keyword.
+func IsKeyword(str string) bool {
+	tokenType, ok := stringToType[str]
+	return ok && isKeyword(tokenType)
+}
+
+// IsOperator reports whether the given string is a krypton operator.
+func IsOperator(str string) bool {
+	tokenType, ok := stringToType[str]
+	return ok && isOperator(tokenType)
+}
+
+// Keywords returns the literals of every keyword, including those
+// added with RegisterKeyword, in sorted order.
+func Keywords() []string {
+	return literalsWhere(isKeyword)
+}
+
+// Operators returns the literals of every operator in sorted order.
+func Operators() []string {
+	return literalsWhere(isOperator)
+}
+
+// literalsWhere returns the sorted literals of the token types
+// matched by the given predicate.
+func literalsWhere(matches func(Type) bool) []string {
+	var literals []string
+	for tokenType, str := range typeToString {
+		if matches(tokenType) {
+			literals = append(literals, str)
+		}
+	}
+
+	sort.Strings(literals)
+	return literals
+}
+
+// ValidateOperatorTable checks the invariant behind the lexer's
+// greedy operator matching: every contiguous prefix of a multi-rune
+// operator must itself be a valid operator. It returns an error
+// listing every violating prefix, or nil if the table is consistent.
+// A test enforces it, protecting the table against operators added
+// later.
+func ValidateOperatorTable() error {
+	var violations []string
+
+	for literal, tokenType := range stringToType {
+		if !isOperator(tokenType) {
+			continue
+		}
+
+		runes := []rune(literal)
+		for i := 1; i < len(runes); i++ {
+			if prefix := string(runes[:i]); !IsOperator(prefix) {
+				violations = append(violations,
+					fmt.Sprintf("operator %q has invalid prefix %q", literal, prefix))
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	sort.Strings(violations)
+	return fmt.Errorf("token: inconsistent operator table:\n\t%s",
+		strings.Join(violations, "\n\t"))
+}
+
+// IsLiteral reports whether the given token type is a literal.
+func IsLiteral(tokenType Type) bool {
+	return literalBeg < tokenType && tokenType < literalEnd
+}
+
+func isKeyword(tokenType Type) bool {
+	return keywordBeg < tokenType && tokenType < keywordEnd ||
+		operatorEnd < tokenType && tokenType < registeredEnd
+}
+
+func isOperator(tokenType Type) bool {
+	return operatorBeg < tokenType && tokenType < operatorEnd
+}
+
+// InsertSemiAfter reports whether a semicolon should be inserted
+// automatically after a token of the given type at the end of a line.
+func InsertSemiAfter(tokenType Type) bool {
+	switch tokenType {
+	case Identifier, Int, Float, Imaginary, String, Rune,
+		Break, Continue, Return, True, False, Nil,
+		RightParen, RightBracket, RightBrace,
+		PlusPlus, MinusMinus:
+		return true
+	default:
+		return false
+	}
+}
+
+// StartsExpression reports whether a token of the given type can
+// begin an expression: an identifier or literal, an opening paren or
+// bracket, an operator usable in prefix position, or a keyword which
+// introduces a value, like func or nil. Parsers use this for
+// semicolon handling and error recovery, where the question is only
+// whether an expression could start at the next token.
+func (tokenType Type) StartsExpression() bool {
+	if IsLiteral(tokenType) {
+		return true
+	}
+
+	switch tokenType {
+	case LeftParen, LeftBracket,
+		Minus, Plus, Bang, Tilde,
+		Func, True, False, Nil:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsAssignment reports whether the token type is the plain
+// assignment operator or one of the compound assignment operators,
+// like += and ??=. Together with IsComparison, it lets tooling flag
+// the classic = vs == confusion, like an assignment lexed where a
+// condition was expected.
+func (tokenType Type) IsAssignment() bool {
+	return tokenType == Equal ||
+		tokenType >= PlusEqual && tokenType <= QuestionQuestionEqual
+}
+
+// IsComparison reports whether the token type is one of the
+// comparison operators: ==, !=, <, >, <=, and >=.
+func (tokenType Type) IsComparison() bool {
+	switch tokenType {
+	case EqualEqual, BangEqual, Less, Greater, LessEqual, GreaterEqual:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsDigit reports whether the given rune is a digit in the given
+// base, which may range from 2 to 36: the letters a to z, in either
+// case, represent the digit values 10 to 35.
+func IsDigit(char rune, base int) bool {
+	var value int
+
+	switch char = unicode.ToLower(char); {
+	case char >= '0' && char <= '9':
+		value = int(char - '0')
+	case char >= 'a' && char <= 'z':
+		value = int(char-'a') + 10
+	default:
+		return false
+	}
+
+	return value < base
+}
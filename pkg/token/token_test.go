@@ -0,0 +1,421 @@
+// Copyright © 2023 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kryptonlang/krypton/pkg/file"
+)
+
+func TestTokenRange(t *testing.T) {
+	tok := Token{
+		Type:    Identifier,
+		Literal: "average",
+		Pos:     file.Pos{Line: 1, Column: 5},
+		End:     file.Pos{Line: 1, Column: 12},
+	}
+
+	span := tok.Range()
+	if span.String() != "1:5-1:12" {
+		t.Errorf("range stringifies to %q, expected %q", span, "1:5-1:12")
+	}
+
+	cases := []struct {
+		pos      file.Pos
+		expected bool
+	}{
+		{file.Pos{Line: 1, Column: 5}, true},
+		{file.Pos{Line: 1, Column: 11}, true},
+		{file.Pos{Line: 1, Column: 12}, false},
+		{file.Pos{Line: 1, Column: 4}, false},
+		{file.Pos{Line: 2, Column: 5}, false},
+	}
+
+	for _, c := range cases {
+		if span.Contains(c.pos) != c.expected {
+			t.Errorf("Contains(%v) = %v, expected %v", c.pos, !c.expected, c.expected)
+		}
+	}
+}
+
+func TestTokenString(t *testing.T) {
+	cases := []struct {
+		token    Token
+		expected string
+	}{
+		{
+			Token{Type: Identifier, Literal: "foo", Pos: file.Pos{Line: 3, Column: 5}},
+			`IDENT("foo") @ 3:5`,
+		},
+		{
+			Token{Type: Plus, Literal: "+", Pos: file.Pos{Line: 1, Column: 9}},
+			"+ @ 1:9",
+		},
+		{
+			Token{Type: Return, Literal: "return", Pos: file.Pos{Line: 7, Column: 2}},
+			"return @ 7:2",
+		},
+	}
+
+	for _, c := range cases {
+		if c.token.String() != c.expected {
+			t.Errorf("token stringifies to %q, expected %q", c.token, c.expected)
+		}
+	}
+}
+
+func TestTypeTextRoundTrip(t *testing.T) {
+	for _, tokenType := range []Type{EOF, Identifier, Plus, StarStarEqual, If, Comment} {
+		text, err := tokenType.MarshalText()
+		if err != nil {
+			t.Errorf("%v: unexpected marshal error: %v", tokenType, err)
+			continue
+		}
+
+		var parsed Type
+		if err := parsed.UnmarshalText(text); err != nil {
+			t.Errorf("%v: unexpected unmarshal error: %v", tokenType, err)
+			continue
+		}
+
+		if parsed != tokenType {
+			t.Errorf("%v round-tripped to %v", tokenType, parsed)
+		}
+	}
+
+	var parsed Type
+	if err := parsed.UnmarshalText([]byte("BOGUS")); err == nil {
+		t.Error("unmarshalling an unknown name did not error")
+	}
+}
+
+func TestIsDigit(t *testing.T) {
+	cases := []struct {
+		char     rune
+		base     int
+		expected bool
+	}{
+		{'0', 2, true},
+		{'1', 2, true},
+		{'2', 2, false},
+		{'2', 3, true},
+		{'7', 3, false},
+		{'7', 8, true},
+		{'8', 8, false},
+		{'9', 10, true},
+		{'a', 10, false},
+		{'f', 16, true},
+		{'F', 16, true},
+		{'g', 16, false},
+		{'g', 17, true},
+		{'z', 36, true},
+		{'Z', 36, true},
+		{'_', 10, false},
+		{'é', 36, false},
+	}
+
+	for _, c := range cases {
+		if IsDigit(c.char, c.base) != c.expected {
+			t.Errorf("IsDigit(%q, %d) = %v, expected %v", c.char, c.base, !c.expected, c.expected)
+		}
+	}
+}
+
+func TestKeywordsOperators(t *testing.T) {
+	keywords := Keywords()
+	if !sort.StringsAreSorted(keywords) {
+		t.Error("Keywords() is not sorted")
+	}
+
+	operators := Operators()
+	if !sort.StringsAreSorted(operators) {
+		t.Error("Operators() is not sorted")
+	}
+
+	for _, keyword := range keywords {
+		if !IsKeyword(keyword) {
+			t.Errorf("Keywords() contains %q, which is not a keyword", keyword)
+		}
+	}
+
+	for _, operator := range operators {
+		if !IsOperator(operator) {
+			t.Errorf("Operators() contains %q, which is not an operator", operator)
+		}
+	}
+
+	index := sort.SearchStrings(keywords, "while")
+	if index == len(keywords) || keywords[index] != "while" {
+		t.Error("Keywords() does not contain \"while\"")
+	}
+
+	index = sort.SearchStrings(operators, "|>")
+	if index == len(operators) || operators[index] != "|>" {
+		t.Error("Operators() does not contain \"|>\"")
+	}
+}
+
+func TestTokenEqual(t *testing.T) {
+	a := Token{Type: Identifier, Literal: "foo", Pos: file.Pos{Line: 1, Column: 1}}
+	b := Token{Type: Identifier, Literal: "foo", Pos: file.Pos{Line: 2, Column: 7}}
+	c := Token{Type: Identifier, Literal: "bar", Pos: file.Pos{Line: 1, Column: 1}}
+
+	if !a.Equal(a, false) {
+		t.Error("a token does not equal itself")
+	}
+
+	if a.Equal(b, false) {
+		t.Error("tokens at different positions compare equal")
+	}
+
+	if !a.Equal(b, true) {
+		t.Error("tokens differing only by position compare unequal with ignorePos")
+	}
+
+	if a.Equal(c, true) {
+		t.Error("tokens with different literals compare equal")
+	}
+}
+
+func TestTokenJSONRoundTrip(t *testing.T) {
+	tok := Token{
+		Type:    Identifier,
+		Literal: "foo",
+		Pos:     file.Pos{Line: 3, Column: 5},
+		End:     file.Pos{Line: 3, Column: 8},
+	}
+
+	marshalled, err := json.Marshal(tok)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+
+	expected := `{"type":"IDENT","literal":"foo",` +
+		`"pos":{"line":3,"column":5},"end":{"line":3,"column":8}}`
+	if string(marshalled) != expected {
+		t.Errorf("marshalled to %s, expected %s", marshalled, expected)
+	}
+
+	var parsed Token
+	if err := json.Unmarshal(marshalled, &parsed); err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+
+	if !parsed.Equal(tok, false) {
+		t.Errorf("round-tripped to %+v, expected %+v", parsed, tok)
+	}
+}
+
+// Keywords registered by tests live in global package state, and
+// RegisterKeyword panics on duplicates by design, so they are
+// registered once per process to keep the suite safe under -count.
+var (
+	yieldKeyword  = RegisterKeyword("yield")
+	unlessKeyword = RegisterKeyword("unless")
+)
+
+func TestRegisterKeyword(t *testing.T) {
+	tokenType := yieldKeyword
+
+	if tokenType.String() != "yield" {
+		t.Errorf("registered type stringifies to %q, expected %q", tokenType, "yield")
+	}
+
+	if Lookup("yield") != tokenType {
+		t.Errorf("Lookup(\"yield\") = %v, expected %v", Lookup("yield"), tokenType)
+	}
+
+	if !IsKeyword("yield") {
+		t.Error("IsKeyword(\"yield\") = false, expected true")
+	}
+}
+
+func TestLookupFold(t *testing.T) {
+	cases := []struct {
+		ident    string
+		expected Type
+	}{
+		{"if", If},
+		{"If", If},
+		{"IF", If},
+		{"rEtUrN", Return},
+		{"İf", Identifier}, // İ has no simple fold onto i
+		{"foo", Identifier},
+	}
+
+	for _, c := range cases {
+		if tokenType := LookupFold(c.ident); tokenType != c.expected {
+			t.Errorf("LookupFold(%q) = %v, expected %v", c.ident, tokenType, c.expected)
+		}
+	}
+}
+
+func TestRegisterKeywordCollision(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering an existing token did not panic")
+		}
+	}()
+
+	RegisterKeyword("if")
+}
+
+func TestCategory(t *testing.T) {
+	cases := []struct {
+		tokenType Type
+		expected  Category
+	}{
+		{Identifier, CategoryLiteral},
+		{Int, CategoryLiteral},
+		{String, CategoryLiteral},
+		{If, CategoryKeyword},
+		{Return, CategoryKeyword},
+		{Plus, CategoryOperator},
+		{LeftParen, CategoryOperator},
+		{QuestionQuestionEqual, CategoryOperator},
+		{Comment, CategoryComment},
+		{DocComment, CategoryComment},
+		{EOF, CategorySpecial},
+		{Illegal, CategorySpecial},
+		{Whitespace, CategorySpecial},
+		{Indent, CategorySpecial},
+		{unlessKeyword, CategoryKeyword},
+	}
+
+	for _, c := range cases {
+		if category := c.tokenType.Category(); category != c.expected {
+			t.Errorf("%v.Category() = %v, expected %v", c.tokenType, category, c.expected)
+		}
+	}
+}
+
+func TestStartsExpression(t *testing.T) {
+	cases := []struct {
+		tokenType Type
+		expected  bool
+	}{
+		{Identifier, true},
+		{Int, true},
+		{String, true},
+		{LeftParen, true},
+		{LeftBracket, true},
+		{Minus, true},
+		{Plus, true},
+		{Bang, true},
+		{Tilde, true},
+		{Func, true},
+		{True, true},
+		{Nil, true},
+		{RightParen, false},
+		{Comma, false},
+		{Star, false},
+		{If, false},
+		{Return, false},
+		{EOF, false},
+		{Comment, false},
+	}
+
+	for _, c := range cases {
+		if starts := c.tokenType.StartsExpression(); starts != c.expected {
+			t.Errorf("%v.StartsExpression() = %v, expected %v", c.tokenType, starts, c.expected)
+		}
+	}
+}
+
+func TestIsAssignmentComparison(t *testing.T) {
+	cases := []struct {
+		tokenType  Type
+		assignment bool
+		comparison bool
+	}{
+		{Equal, true, false},
+		{PlusEqual, true, false},
+		{LessLessEqual, true, false},
+		{QuestionQuestionEqual, true, false},
+		{EqualEqual, false, true},
+		{BangEqual, false, true},
+		{Less, false, true},
+		{GreaterEqual, false, true},
+		{Plus, false, false},
+		{Identifier, false, false},
+		{If, false, false},
+	}
+
+	for _, c := range cases {
+		if assignment := c.tokenType.IsAssignment(); assignment != c.assignment {
+			t.Errorf("%v.IsAssignment() = %v, expected %v", c.tokenType, assignment, c.assignment)
+		}
+
+		if comparison := c.tokenType.IsComparison(); comparison != c.comparison {
+			t.Errorf("%v.IsComparison() = %v, expected %v", c.tokenType, comparison, c.comparison)
+		}
+	}
+}
+
+func TestOperatorPrefixes(t *testing.T) {
+	// the lexer lexes operators greedily, which only works when every
+	// contiguous prefix of a multi-rune operator is itself a valid
+	// operator
+	for _, operator := range Operators() {
+		runes := []rune(operator)
+		for i := 1; i < len(runes); i++ {
+			if prefix := string(runes[:i]); !IsOperator(prefix) {
+				t.Errorf("operator %q has invalid prefix %q", operator, prefix)
+			}
+		}
+	}
+}
+
+func TestValidateOperatorTable(t *testing.T) {
+	if err := ValidateOperatorTable(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	// an operator whose prefix is not an operator must be flagged
+	stringToType["$="] = Plus
+	defer delete(stringToType, "$=")
+
+	err := ValidateOperatorTable()
+	if err == nil {
+		t.Fatal("a violating operator passed validation")
+	}
+
+	if !strings.Contains(err.Error(), `"$="`) {
+		t.Errorf("error %q does not name the violating operator", err)
+	}
+}
+
+func TestNewTokenType(t *testing.T) {
+	cases := []struct {
+		str      string
+		expected Type
+	}{
+		{"+", Plus},
+		{"??=", QuestionQuestionEqual},
+		{"IDENT", Identifier},
+		{"if", If},
+		{"EOF", EOF},
+		{"bogus", Illegal},
+		{"", Illegal},
+	}
+
+	for _, c := range cases {
+		if tokenType := NewTokenType(c.str); tokenType != c.expected {
+			t.Errorf("NewTokenType(%q) = %v, expected %v", c.str, tokenType, c.expected)
+		}
+	}
+}